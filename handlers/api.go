@@ -2,86 +2,266 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gowaze/models"
+	"gowaze/routing"
 	"gowaze/services"
 	"gowaze/utils"
+	"html/template"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxReportRequestSize tamaño máximo de la solicitud que crea un reporte con sus fotos
+	maxReportRequestSize = services.MaxPhotosPerReport*services.MaxPhotoSize + 1<<20
+	// reportFormMemory bytes de un formulario multipart que se leen en memoria; el
+	// resto se guarda en archivos temporales
+	reportFormMemory = 8 << 20
+	// maxMatchRequestSize tamaño máximo del JSON de una traza: holgura de 256 bytes por punto
+	maxMatchRequestSize = services.MaxTracePoints*256 + 1<<10
 )
 
 // APIHandler maneja las rutas de la API REST
 type APIHandler struct {
-	storage   *services.Storage
-	wsService *services.WebSocketService
+	storage        services.Storage
+	wsService      *services.WebSocketService
+	routingService *services.RoutingService
+	photoService   *services.PhotoService
+	sessions       *services.SessionStore
 }
 
 // NewAPIHandler crea una nueva instancia del handler de API
-func NewAPIHandler(storage *services.Storage, wsService *services.WebSocketService) *APIHandler {
+func NewAPIHandler(storage services.Storage, wsService *services.WebSocketService, routingService *services.RoutingService, photoService *services.PhotoService, sessions *services.SessionStore) *APIHandler {
 	return &APIHandler{
-		storage:   storage,
-		wsService: wsService,
+		storage:        storage,
+		wsService:      wsService,
+		routingService: routingService,
+		photoService:   photoService,
+		sessions:       sessions,
 	}
 }
 
-// CreateUserHandler maneja la creación/actualización de usuarios
+// CreateUserHandler maneja la creación/actualización de usuarios y abre su sesión.
+// Responde 429 si la dirección del cliente ya abrió services.MaxSessionsPerClient
+// sesiones en services.SessionIssueWindow
 func (h *APIHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	username := r.FormValue("username")
 	lat, _ := strconv.ParseFloat(r.FormValue("lat"), 64)
 	lng, _ := strconv.ParseFloat(r.FormValue("lng"), 64)
 
 	if username == "" {
-		http.Error(w, "Username es requerido", http.StatusBadRequest)
+		httpError(w, r, "Username es requerido", http.StatusBadRequest)
+		return
+	}
+	// Cada registro abre una sesión con la que se vota; se limita por dirección
+	if err := h.sessions.Reserve(clientAddr(r)); err != nil {
+		httpError(w, r, err.Error(), http.StatusTooManyRequests)
 		return
 	}
 
-	user := h.storage.CreateUser(username, lat, lng)
+	user, err := h.storage.CreateUser(username, lat, lng)
+	if err != nil {
+		log.Printf("Error guardando usuario: %v", err)
+		httpError(w, r, "Error guardando usuario", http.StatusInternalServerError)
+		return
+	}
+	if err := startSession(w, r, h.sessions, user.ID); err != nil {
+		log.Printf("Error abriendo sesión: %v", err)
+		httpError(w, r, "Error abriendo sesión", http.StatusInternalServerError)
+		return
+	}
 
 	// Broadcast actualización de estadísticas
 	h.wsService.BroadcastStats()
 
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusCreated, user)
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/html")
 	fmt.Fprintf(w, `<div style="color: green; margin-top: 10px;">✅ Usuario "%s" registrado en (%.6f, %.6f)</div>`, 
 		user.Username, user.Lat, user.Lng)
 }
 
-// CreateReportHandler maneja la creación de reportes
+// CreateReportHandler maneja la creación de reportes. Acepta formularios simples o
+// multipart con hasta services.MaxPhotosPerReport fotos en el campo photos
 func (h *APIHandler) CreateReportHandler(w http.ResponseWriter, r *http.Request) {
-	reportType := r.FormValue("type")
-	lat, _ := strconv.ParseFloat(r.FormValue("lat"), 64)
-	lng, _ := strconv.ParseFloat(r.FormValue("lng"), 64)
-	description := r.FormValue("description")
+	r.Body = http.MaxBytesReader(w, r.Body, maxReportRequestSize)
+	if err := r.ParseMultipartForm(reportFormMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, r, fmt.Sprintf("Solicitud demasiado grande: máximo %d MB", maxReportRequestSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		httpError(w, r, "Formulario inválido", http.StatusBadRequest)
+		return
+	}
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	}
 
-	if reportType == "" {
-		http.Error(w, "Tipo de reporte es requerido", http.StatusBadRequest)
+	draft, err := parseReport(r)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Sin sesión el reporte es anónimo (UserID 0)
+	draft.UserID, _ = sessionUser(h.sessions, r)
+
+	// Las fotos se procesan después de validar el reporte para no guardar las de uno inválido
+	if r.MultipartForm != nil && len(r.MultipartForm.File["photos"]) > 0 {
+		photos, err := h.photoService.Save(r.MultipartForm.File["photos"])
+		switch {
+		case errors.Is(err, services.ErrTooManyPhotos):
+			httpError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrPhotoTooLarge):
+			httpError(w, r, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		case errors.Is(err, services.ErrUnsupportedPhoto):
+			httpError(w, r, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		case err != nil:
+			log.Printf("Error guardando fotos: %v", err)
+			httpError(w, r, "Error guardando fotos", http.StatusInternalServerError)
+			return
+		}
+		draft.Photos = photos
+	}
+
+	// Si falla, las fotos ya guardadas quedan huérfanas y se borran con la limpieza periódica
+	report, merged, err := h.storage.CreateReport(draft)
+	if err != nil {
+		log.Printf("Error guardando reporte: %v", err)
+		httpError(w, r, "Error guardando reporte", http.StatusInternalServerError)
+		return
+	}
+
+	// Un duplicado actualiza el incidente existente en lugar de crear uno nuevo
+	if merged {
+		h.wsService.PublishVote(report, false)
+	} else {
+		h.wsService.BroadcastNewReport(report)
+	}
+
+	if wantsJSON(r) {
+		status := http.StatusCreated
+		if merged {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, report)
+		return
+	}
+
+	// Devolver lista actualizada de reportes; lat y lng del formulario son la
+	// ubicación del reporte, no un filtro
+	h.writeReports(w, r, services.ReportQuery{})
+}
+
+// PhotoHandler sirve una foto o miniatura de un reporte
+func (h *APIHandler) PhotoHandler(w http.ResponseWriter, r *http.Request) {
+	data, contentType, err := h.photoService.Open(mux.Vars(r)["key"])
+	if errors.Is(err, services.ErrBlobNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error leyendo foto", http.StatusInternalServerError)
 		return
 	}
 
-	// Validar tipo de reporte
-	validTypes := map[string]bool{
-		"accident": true,
-		"police":   true,
-		"traffic":  true,
-		"hazard":   true,
+	// Cada foto tiene una clave única y nunca cambia
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
+
+// ReportTypesHandler publica el registro de tipos y subtipos de reporte con sus
+// iconos, vida y gravedad por defecto
+func (h *APIHandler) ReportTypesHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, services.ReportTypes())
+}
+
+// VoteResult respuesta JSON de un voto sobre un reporte
+type VoteResult struct {
+	Report  *models.Report `json:"report"`
+	Retired bool           `json:"retired"` // el reporte se retiró por votos negativos
+}
+
+// UpvoteReportHandler confirma que un reporte sigue vigente ("sigue ahí")
+func (h *APIHandler) UpvoteReportHandler(w http.ResponseWriter, r *http.Request) {
+	h.voteReport(w, r, services.VoteUp)
+}
+
+// DownvoteReportHandler indica que un reporte ya no está vigente ("ya no está")
+func (h *APIHandler) DownvoteReportHandler(w http.ResponseWriter, r *http.Request) {
+	h.voteReport(w, r, services.VoteDown)
+}
+
+// voteReport registra el voto del usuario de la sesión sobre el reporte {id} y difunde
+// el resultado
+func (h *APIHandler) voteReport(w http.ResponseWriter, r *http.Request, vote int) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["id"])
+	userID, ok := sessionUser(h.sessions, r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		httpError(w, r, services.ErrNoSession.Error(), http.StatusUnauthorized)
+		return
 	}
 
-	if !validTypes[reportType] {
-		http.Error(w, "Tipo de reporte inválido", http.StatusBadRequest)
+	report, retired, err := h.storage.VoteReport(reportID, userID, vote)
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		httpError(w, r, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrAlreadyVoted):
+		httpError(w, r, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		httpError(w, r, "Error registrando voto", http.StatusInternalServerError)
 		return
 	}
 
-	report := h.storage.CreateReport(reportType, lat, lng, description, 1) // UserID por defecto
+	// Broadcast del reporte actualizado o retirado
+	h.wsService.PublishVote(report, retired)
 
-	// Broadcast nuevo reporte
-	h.wsService.BroadcastNewReport(report)
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, VoteResult{Report: report, Retired: retired})
+		return
+	}
 
-	// Devolver lista actualizada de reportes
-	h.GetReportsHandler(w, r)
+	// Devolver lista actualizada de reportes, sin tomar los campos del voto como filtros
+	h.writeReports(w, r, services.ReportQuery{})
 }
 
 // GetReportsHandler maneja la obtención de reportes
 func (h *APIHandler) GetReportsHandler(w http.ResponseWriter, r *http.Request) {
-	reports := h.storage.GetRecentReports()
+	query, err := parseReportQuery(r)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeReports(w, r, query)
+}
+
+// writeReports responde los reportes que cumplen una búsqueda como JSON o como lista HTML
+func (h *APIHandler) writeReports(w http.ResponseWriter, r *http.Request, query services.ReportQuery) {
+	reports, total := services.FindReports(h.storage, query)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, ReportsPage{Reports: reports, Total: total, Offset: query.Offset, Limit: len(reports)})
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/html")
 	html := `<div class="reports-list">`
@@ -91,83 +271,796 @@ func (h *APIHandler) GetReportsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, report := range reports {
-		icon := getReportIcon(report.Type)
+		icon := services.ReportIcon(report.Type, report.Subtype)
+
+		// Los incidentes agrupados muestran todas las descripciones y cuántos reportes
+		// suman. Las descripciones vienen de los usuarios y se escapan
+		descriptions := report.Descriptions
+		if len(descriptions) == 0 {
+			descriptions = []string{report.Description}
+		}
+		escaped := make([]string, len(descriptions))
+		for i, d := range descriptions {
+			escaped[i] = template.HTMLEscapeString(d)
+		}
+		description := strings.Join(escaped, " · ")
+		if len(report.MergedIDs) > 0 {
+			description += fmt.Sprintf(` <span style="color: #666;">(%d reportes)</span>`, len(report.MergedIDs)+1)
+		}
+
+		// Gravedad, sentido y carriles afectados
+		details := fmt.Sprintf("Gravedad %d/%d", report.Severity, services.MaxSeverity)
+		if report.Direction != "" {
+			details += " | Sentido " + report.Direction
+		}
+		if len(report.Lanes) > 0 {
+			lanes := make([]string, len(report.Lanes))
+			for i, lane := range report.Lanes {
+				lanes[i] = strconv.Itoa(lane)
+			}
+			details += " | Carriles " + strings.Join(lanes, ", ")
+		}
+
+		// Miniaturas que abren la foto completa
+		photos := ""
+		for _, photo := range report.Photos {
+			photos += fmt.Sprintf(`<a href="%s" target="_blank"><img src="%s" alt="Foto del reporte" style="height: 48px; margin-right: 4px; border-radius: 4px;"></a>`,
+				photo.URL, photo.ThumbnailURL)
+		}
+		if photos != "" {
+			photos = `<div style="margin-top: 4px;">` + photos + `</div>`
+		}
 
 		html += fmt.Sprintf(`
 			<div class="report-item">
 				<div class="report-type">%s %s</div>
 				<div>%s</div>
+				<div style="color: #666; font-size: 0.8em;">%s</div>%s
 				<div class="coordinates">📍 %.6f, %.6f</div>
-				<div style="color: #666; font-size: 0.8em;">%s | 👍 %d votos</div>
+				<div style="color: #666; font-size: 0.8em;">%s | 👍 %d votos | 🎯 %.0f%% | ⏳ hasta %s</div>
 			</div>
-		`, icon, report.Type, report.Description, report.Lat, report.Lng, 
-		   report.CreatedAt.Format("15:04"), report.Votes)
+		`, icon, services.ReportLabel(report.Type, report.Subtype), description, details, photos, report.Lat, report.Lng, 
+		   report.CreatedAt.Format("15:04"), report.Votes, report.Confidence*100, report.ExpiresAt.Format("15:04"))
+	}
+
+	if shown := query.Offset + len(reports); shown < total {
+		html += fmt.Sprintf(`<div style="text-align: center; color: #666; font-size: 0.8em;">Mostrando %d de %d reportes</div>`, shown, total)
 	}
 
 	html += `</div>`
 	fmt.Fprint(w, html)
 }
 
-// CalculateRouteHandler maneja el cálculo de rutas
-func (h *APIHandler) CalculateRouteHandler(w http.ResponseWriter, r *http.Request) {
-	fromLat, _ := strconv.ParseFloat(r.FormValue("from_lat"), 64)
-	fromLng, _ := strconv.ParseFloat(r.FormValue("from_lng"), 64)
-	toLat, _ := strconv.ParseFloat(r.FormValue("to_lat"), 64)
-	toLng, _ := strconv.ParseFloat(r.FormValue("to_lng"), 64)
+// ReportsPage página de reportes de la respuesta JSON de /api/reports
+type ReportsPage struct {
+	Reports []*models.Report `json:"reports"`
+	Total   int              `json:"total"`  // coincidencias sin paginar
+	Offset  int              `json:"offset"` // posición del primer reporte de la página
+	Limit   int              `json:"limit"`  // reportes en la página
+}
 
-	// Validar coordenadas
-	if fromLat == 0 || fromLng == 0 || toLat == 0 || toLng == 0 {
-		http.Error(w, "Coordenadas inválidas", http.StatusBadRequest)
-		return
+// parseReport lee un reporte por crear: type y subtype del registro de tipos,
+// lat/lng, description, severity (1-5, por defecto la del tipo), direction
+// (N, NE, ..., NW o both) y lanes (carriles afectados desde la izquierda, p. ej. 1,2)
+func parseReport(r *http.Request) (*models.Report, error) {
+	lat, _ := strconv.ParseFloat(r.FormValue("lat"), 64)
+	lng, _ := strconv.ParseFloat(r.FormValue("lng"), 64)
+	draft := &models.Report{
+		Type:        r.FormValue("type"),
+		Subtype:     r.FormValue("subtype"),
+		Direction:   r.FormValue("direction"),
+		Lat:         lat,
+		Lng:         lng,
+		Description: r.FormValue("description"),
+	}
+
+	if draft.Type == "" {
+		return nil, errors.New("Tipo de reporte es requerido")
 	}
 
-	// Calcular distancia usando fórmula haversine
-	distance := utils.HaversineDistance(fromLat, fromLng, toLat, toLng)
+	if value := r.FormValue("severity"); value != "" {
+		severity, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.New("gravedad inválida")
+		}
+		draft.Severity = severity
+	}
 
-	// Estimar duración (asumiendo velocidad promedio de 50 km/h en ciudad)
-	duration := int(distance / 50 * 60) // en minutos
+	for _, value := range strings.Split(r.FormValue("lanes"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		lane, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("carril inválido %q", value)
+		}
+		draft.Lanes = append(draft.Lanes, lane)
+	}
 
-	// Simular puntos de ruta (línea recta dividida en segmentos)
-	points := make([]models.Location, 0)
-	segments := 10
-	for i := 0; i <= segments; i++ {
-		ratio := float64(i) / float64(segments)
-		lat := fromLat + (toLat-fromLat)*ratio
-		lng := fromLng + (toLng-fromLng)*ratio
-		points = append(points, models.Location{Lat: lat, Lng: lng})
+	if err := services.ValidateReport(draft); err != nil {
+		return nil, err
 	}
+	return draft, nil
+}
+
+// parseReportQuery interpreta los filtros de /api/reports:
+//   - bbox: "oeste,sur,este,norte" (lng,lat,lng,lat, como Leaflet toBBoxString)
+//   - lat, lng, radius: centro y radio en metros
+//   - types: tipos separados por comas
+//   - since, until: horas RFC 3339
+//   - min_votes, limit, offset
+func parseReportQuery(r *http.Request) (services.ReportQuery, error) {
+	var query services.ReportQuery
 
-	route := models.Route{
-		From:     models.Location{Lat: fromLat, Lng: fromLng},
-		To:       models.Location{Lat: toLat, Lng: toLng},
-		Points:   points,
-		Distance: distance,
-		Duration: duration,
+	if value := r.FormValue("bbox"); value != "" {
+		parts := strings.Split(value, ",")
+		coords := make([]float64, len(parts))
+		for i, part := range parts {
+			coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return query, errors.New("bbox inválido: use oeste,sur,este,norte")
+			}
+			coords[i] = coord
+		}
+		if len(coords) != 4 || !utils.ValidateCoordinates(coords[1], coords[0]) || !utils.ValidateCoordinates(coords[3], coords[2]) ||
+			coords[0] > coords[2] || coords[1] > coords[3] {
+			return query, errors.New("bbox inválido: use oeste,sur,este,norte")
+		}
+		query.BBox = &utils.BBox{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}
+	}
+
+	if r.FormValue("lat") != "" || r.FormValue("lng") != "" || r.FormValue("radius") != "" {
+		lat, errLat := strconv.ParseFloat(r.FormValue("lat"), 64)
+		lng, errLng := strconv.ParseFloat(r.FormValue("lng"), 64)
+		if errLat != nil || errLng != nil || !utils.ValidateCoordinates(lat, lng) {
+			return query, errors.New("Centro inválido: indique lat y lng")
+		}
+		radius, err := strconv.ParseFloat(r.FormValue("radius"), 64)
+		if err != nil || radius <= 0 || radius/1000 > services.MaxReportsRadius {
+			return query, fmt.Errorf("Radio inválido: entre 1 y %.0f metros", services.MaxReportsRadius*1000)
+		}
+		query.Center = &models.Location{Lat: lat, Lng: lng}
+		query.Radius = radius / 1000
+	}
+
+	for _, t := range strings.Split(r.FormValue("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			query.Types = append(query.Types, t)
+		}
+	}
+
+	var err error
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		if value := r.FormValue(param.name); value != "" {
+			if *param.target, err = time.Parse(time.RFC3339, value); err != nil {
+				return query, fmt.Errorf("%s inválido: use RFC 3339", param.name)
+			}
+		}
+	}
+
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"min_votes", &query.MinVotes}, {"limit", &query.Limit}, {"offset", &query.Offset}} {
+		if value := r.FormValue(param.name); value != "" {
+			if *param.target, err = strconv.Atoi(value); err != nil || *param.target < 0 {
+				return query, fmt.Errorf("%s inválido", param.name)
+			}
+		}
+	}
+	if query.Limit > services.MaxReportsLimit {
+		return query, fmt.Errorf("limit máximo %d", services.MaxReportsLimit)
 	}
 
+	return query, nil
+}
+
+// CalculateRouteHandler maneja el cálculo de rutas
+func (h *APIHandler) CalculateRouteHandler(w http.ResponseWriter, r *http.Request) {
+	req, profile, err := parseRouteRequest(r)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	route, method, ok := h.calculateRoute(w, r, req, profile)
+	if !ok {
+		return
+	}
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, route)
+		return
+	}
+	fromLat, fromLng, toLat, toLng := req.From.Lat, req.From.Lng, req.To.Lat, req.To.Lng
+
 	w.Header().Set("Content-Type", "text/html")
 	html := fmt.Sprintf(`
-		<div class="route-info">
+		<div class="route-info" data-polyline="%s" data-precision="%d">
 			<h4>📍 Ruta Calculada</h4>
+			<p><strong>%s Perfil:</strong> %s</p>
 			<p><strong>📏 Distancia:</strong> %.2f km</p>
 			<p><strong>⏱️ Tiempo estimado:</strong> %d minutos</p>
+			<p><strong>🕐 Salida:</strong> %s · <strong>Llegada:</strong> %s</p>
+			<p><strong>🚦 Demora por tráfico:</strong> %d minutos %s</p>
 			<p><strong>🅰️ Desde:</strong> %.6f, %.6f</p>
 			<p><strong>🅱️ Hasta:</strong> %.6f, %.6f</p>
 			<p><strong>📊 Puntos de ruta:</strong> %d</p>
+			%s
+			%s
+			%s
 			<div style="margin-top: 10px;">
-				<small style="color: #666;">💡 Usando algoritmo Haversine + OpenStreetMap</small>
+				<small style="color: #666;">💡 %s</small>
 			</div>
 		</div>
-	`, route.Distance, route.Duration, fromLat, fromLng, toLat, toLng, len(route.Points))
+	`, template.HTMLEscapeString(route.Polyline), route.Precision, profileIcon(route.Profile), route.Profile, route.Distance, route.Duration,
+		route.DepartAt.Format("02/01 15:04"), route.ArriveAt.Format("02/01 15:04"), route.Delay, congestionBadge(route.Congestion),
+		fromLat, fromLng, toLat, toLng, len(route.Points), legsHTML(route.Legs),
+		stepsHTML(route.Steps), alternativesHTML(route.Alternatives), method)
 
 	fmt.Fprint(w, html)
 }
 
+// ExportRouteHandler calcula una ruta con los mismos parámetros que /api/routes y la
+// descarga en formato GPX 1.1 o KML (format=gpx|kml)
+func (h *APIHandler) ExportRouteHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	req, profile, err := parseRouteRequest(r)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Alternatives = 0
+	req.OmitPoints = false
+
+	route, _, ok := h.calculateRoute(w, r, req, profile)
+	if !ok {
+		return
+	}
+
+	name := fmt.Sprintf("Ruta GoWaze (%.5f, %.5f) → (%.5f, %.5f)", route.From.Lat, route.From.Lng, route.To.Lat, route.To.Lng)
+	writeExport(w, format, "ruta",
+		func() error { return utils.WriteRouteGPX(w, route, name) },
+		func() error { return utils.WriteRouteKML(w, route, name) })
+}
+
+// GetTripsHandler lista en JSON los viajes registrados en sesiones de navegación, sin sus puntos
+func (h *APIHandler) GetTripsHandler(w http.ResponseWriter, r *http.Request) {
+	trips := h.storage.GetTrips()
+	summaries := make([]models.Trip, len(trips))
+	for i, trip := range trips {
+		summaries[i] = *trip
+		summaries[i].Points = nil
+	}
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// ExportTripHandler descarga un viaje registrado en formato GPX 1.1 o KML (format=gpx|kml)
+func (h *APIHandler) ExportTripHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httpError(w, r, "ID de viaje inválido", http.StatusBadRequest)
+		return
+	}
+	trip, found := h.storage.GetTrip(id)
+	if !found {
+		httpError(w, r, "Viaje no encontrado", http.StatusNotFound)
+		return
+	}
+
+	name := fmt.Sprintf("Viaje GoWaze %d (%s)", trip.ID, trip.StartedAt.Format("2006-01-02 15:04"))
+	writeExport(w, format, fmt.Sprintf("viaje-%d", trip.ID),
+		func() error { return utils.WriteTripGPX(w, trip, name) },
+		func() error { return utils.WriteTripKML(w, trip, name) })
+}
+
+// exportFormat valida el formato de exportación solicitado ("gpx" por defecto)
+func exportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = "gpx"
+	}
+	if format != "gpx" && format != "kml" {
+		httpError(w, r, "Formato inválido. Opciones: gpx, kml", http.StatusBadRequest)
+		return "", false
+	}
+	return format, true
+}
+
+// writeExport escribe un archivo de exportación como descarga
+func writeExport(w http.ResponseWriter, format, filename string, writeGPX, writeKML func() error) {
+	write := writeGPX
+	w.Header().Set("Content-Type", "application/gpx+xml")
+	if format == "kml" {
+		write = writeKML
+		w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+
+	if err := write(); err != nil {
+		log.Printf("Error exportando %s: %v", filename, err)
+	}
+}
+
+// parseRouteRequest interpreta los parámetros de cálculo de una ruta. Los errores
+// retornados describen el parámetro inválido para el usuario
+func parseRouteRequest(r *http.Request) (services.RouteRequest, *routing.Profile, error) {
+	var req services.RouteRequest
+	fromLat, _ := strconv.ParseFloat(r.FormValue("from_lat"), 64)
+	fromLng, _ := strconv.ParseFloat(r.FormValue("from_lng"), 64)
+	toLat, _ := strconv.ParseFloat(r.FormValue("to_lat"), 64)
+	toLng, _ := strconv.ParseFloat(r.FormValue("to_lng"), 64)
+
+	// Validar coordenadas
+	if fromLat == 0 || fromLng == 0 || toLat == 0 || toLng == 0 {
+		return req, nil, errors.New("Coordenadas inválidas")
+	}
+
+	waypoints, err := parseLocations(r.FormValue("waypoints"))
+	if err != nil {
+		return req, nil, errors.New("Paradas intermedias inválidas: " + err.Error())
+	}
+	if len(waypoints) > services.MaxWaypoints {
+		return req, nil, fmt.Errorf("Máximo %d paradas intermedias", services.MaxWaypoints)
+	}
+
+	profile, err := routing.ProfileByName(r.FormValue("profile"))
+	if err != nil {
+		return req, nil, errors.New("Perfil inválido. Opciones: " + strings.Join(routing.ProfileNames(), ", "))
+	}
+	height, _ := strconv.ParseFloat(r.FormValue("height"), 64)
+	weight, _ := strconv.ParseFloat(r.FormValue("weight"), 64)
+
+	avoid, avoidReports, err := parseAvoid(r)
+	if err != nil {
+		return req, nil, errors.New("Restricciones inválidas: " + err.Error())
+	}
+
+	precision := utils.PolylinePrecision
+	if value := r.FormValue("polyline_precision"); value != "" {
+		precision, err = strconv.Atoi(value)
+		if err != nil || (precision != utils.PolylinePrecision && precision != utils.PolylinePrecision6) {
+			return req, nil, errors.New("Precisión de polilínea inválida (5 o 6)")
+		}
+	}
+
+	departAt, err := parseTime(r.FormValue("depart_at"))
+	if err != nil {
+		return req, nil, errors.New("Hora de salida inválida: " + err.Error())
+	}
+	arriveBy, err := parseTime(r.FormValue("arrive_by"))
+	if err != nil {
+		return req, nil, errors.New("Hora de llegada inválida: " + err.Error())
+	}
+	if !departAt.IsZero() && !arriveBy.IsZero() {
+		return req, nil, errors.New("Indique solo hora de salida (depart_at) o de llegada (arrive_by)")
+	}
+
+	alternatives, _ := strconv.Atoi(r.FormValue("alternatives"))
+	optimize, _ := strconv.ParseBool(r.FormValue("optimize"))
+
+	req = services.RouteRequest{
+		From:         models.Location{Lat: fromLat, Lng: fromLng},
+		To:           models.Location{Lat: toLat, Lng: toLng},
+		Waypoints:    waypoints,
+		Optimize:     optimize,
+		Alternatives: alternatives,
+		Language:     r.FormValue("lang"),
+		Profile:      profile.Name,
+		Height:       height,
+		Weight:       weight,
+		Avoid:        avoid,
+		AvoidReports: avoidReports,
+		Precision:    precision,
+		OmitPoints:   r.FormValue("points") == "false",
+		DepartAt:     departAt,
+		ArriveBy:     arriveBy,
+	}
+	return req, profile, nil
+}
+
+// parseTime interpreta una hora RFC 3339 o "HH:MM" en hora local. Una hora "HH:MM"
+// ya pasada se toma como la del día siguiente. Un valor vacío retorna la hora cero
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	clock, err := time.ParseInLocation("15:04", value, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("use RFC 3339 o HH:MM")
+	}
+	now := time.Now()
+	t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+	if t.Before(now.Add(-time.Minute)) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// calculateRoute calcula una ruta y describe el método usado. Si no hay red vial
+// estima la ruta en línea recta. Ante un error responde al cliente y retorna false
+func (h *APIHandler) calculateRoute(w http.ResponseWriter, r *http.Request, req services.RouteRequest, profile *routing.Profile) (*models.Route, string, bool) {
+	method := "Ruta sobre red vial OpenStreetMap (A*) con tráfico en vivo"
+	route, err := h.routingService.CalculateRoute(req)
+	switch {
+	case errors.Is(err, routing.ErrNoGraph):
+		// Sin red vial cargada: estimación en línea recta
+		route = straightLineRoute(req.From, req.Waypoints, req.To, profile)
+		services.SetGeometry(route, req.Precision, req.OmitPoints)
+		services.Schedule(route, req)
+		method = "Estimación en línea recta (sin red vial cargada)"
+	case errors.Is(err, routing.ErrNoSnap), errors.Is(err, routing.ErrNoRoute):
+		httpError(w, r, "No se pudo calcular la ruta: "+err.Error(), http.StatusUnprocessableEntity)
+		return nil, "", false
+	case err != nil:
+		httpError(w, r, "Error calculando ruta", http.StatusInternalServerError)
+		return nil, "", false
+	}
+	return route, method, true
+}
+
+// stepsHTML genera la lista de instrucciones paso a paso
+func stepsHTML(steps []models.RouteStep) string {
+	if len(steps) == 0 {
+		return ""
+	}
+
+	html := `<div class="route-steps"><p><strong>🧭 Indicaciones:</strong></p><ol>`
+	for _, step := range steps {
+		distance := ""
+		if step.Distance > 0 {
+			distance = fmt.Sprintf(` <small style="color: #666;">(%s)</small>`, utils.FormatDistance(step.Distance))
+		}
+		html += fmt.Sprintf(`
+			<li>%s%s</li>`, template.HTMLEscapeString(step.Instruction), distance)
+	}
+	return html + `</ol></div>`
+}
+
+// legsHTML genera la lista de tramos entre paradas de una ruta con paradas intermedias
+func legsHTML(legs []models.RouteLeg) string {
+	if len(legs) == 0 {
+		return ""
+	}
+
+	html := `<div class="route-legs"><p><strong>🛑 Paradas:</strong></p><ol>`
+	for _, leg := range legs {
+		html += fmt.Sprintf(`
+			<li>(%.6f, %.6f) → (%.6f, %.6f): %.2f km · %d min</li>`,
+			leg.From.Lat, leg.From.Lng, leg.To.Lat, leg.To.Lng, leg.Distance, leg.Duration)
+	}
+	return html + `</ol></div>`
+}
+
+// alternativesHTML genera la lista de rutas alternativas
+func alternativesHTML(alternatives []models.Route) string {
+	if len(alternatives) == 0 {
+		return ""
+	}
+
+	html := `<div class="route-alternatives"><p><strong>🔀 Alternativas:</strong></p>`
+	for _, alt := range alternatives {
+		html += fmt.Sprintf(`
+			<div class="route-alternative">
+				Ruta %d: %.2f km · %d min (+%d min tráfico) %s
+			</div>`, alt.ID+1, alt.Distance, alt.Duration, alt.Delay, congestionBadge(alt.Congestion))
+	}
+	return html + `</div>`
+}
+
+// profileIcon retorna el emoji correspondiente al perfil de viaje
+func profileIcon(profile string) string {
+	icons := map[string]string{
+		"car":        "🚗",
+		"motorcycle": "🏍️",
+		"truck":      "🚚",
+		"bicycle":    "🚲",
+		"walking":    "🚶",
+	}
+
+	if icon, exists := icons[profile]; exists {
+		return icon
+	}
+	return "🚗"
+}
+
+// congestionBadge retorna el indicador de congestión de una ruta
+func congestionBadge(congestion *models.CongestionSummary) string {
+	if congestion == nil {
+		return ""
+	}
+
+	badges := map[string]string{
+		"low":    "🟢 fluido",
+		"medium": "🟡 moderado",
+		"high":   "🔴 pesado",
+	}
+	return badges[congestion.Level]
+}
+
+// straightLineRoute estima una ruta en línea recta cuando no hay red vial disponible
+func straightLineRoute(from models.Location, waypoints []models.Location, to models.Location, profile *routing.Profile) *models.Route {
+	stops := append(append([]models.Location{from}, waypoints...), to)
+	route := &models.Route{
+		From:      from,
+		To:        to,
+		Points:    []models.Location{from},
+		Waypoints: waypoints,
+		Profile:   profile.Name,
+	}
+
+	for i := 0; i+1 < len(stops); i++ {
+		a, b := stops[i], stops[i+1]
+
+		// Calcular distancia usando fórmula haversine
+		distance := utils.HaversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+
+		// Estimar duración con la velocidad promedio del perfil
+		duration := int(distance / profile.TypicalSpeed * 60) // en minutos
+
+		// Simular puntos de ruta (línea recta dividida en segmentos)
+		segments := 10
+		for j := 1; j <= segments; j++ {
+			ratio := float64(j) / float64(segments)
+			lat := a.Lat + (b.Lat-a.Lat)*ratio
+			lng := a.Lng + (b.Lng-a.Lng)*ratio
+			route.Points = append(route.Points, models.Location{Lat: lat, Lng: lng})
+		}
+
+		route.Distance += distance
+		route.Duration += duration
+		if len(waypoints) > 0 {
+			route.Legs = append(route.Legs, models.RouteLeg{From: a, To: b, Distance: distance, Duration: duration})
+		}
+	}
+
+	return route
+}
+
+// parseLocations interpreta una lista de coordenadas "lat,lng;lat,lng"
+func parseLocations(value string) ([]models.Location, error) {
+	var locations []models.Location
+	for _, pair := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '|' }) {
+		parts := strings.Split(strings.TrimSpace(pair), ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("coordenada inválida %q", pair)
+		}
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lng, errLng := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat != nil || errLng != nil || !utils.ValidateCoordinates(lat, lng) {
+			return nil, fmt.Errorf("coordenada inválida %q", pair)
+		}
+		locations = append(locations, models.Location{Lat: lat, Lng: lng})
+	}
+	return locations, nil
+}
+
+// parseAvoid interpreta las restricciones de una ruta:
+//   - avoid: "tolls,unpaved"
+//   - avoid_polygons: polígonos "lat,lng;lat,lng;lat,lng" separados por "|"
+//   - avoid_reports: "tipo:radio" en metros separados por comas, p. ej. "police:500,hazard:200"
+func parseAvoid(r *http.Request) (routing.Avoid, map[string]float64, error) {
+	var avoid routing.Avoid
+	for _, feature := range strings.Split(r.FormValue("avoid"), ",") {
+		switch strings.ToLower(strings.TrimSpace(feature)) {
+		case "":
+		case "tolls", "toll":
+			avoid.Tolls = true
+		case "unpaved":
+			avoid.Unpaved = true
+		default:
+			return avoid, nil, fmt.Errorf("opción desconocida %q", feature)
+		}
+	}
+
+	for _, value := range strings.Split(r.FormValue("avoid_polygons"), "|") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		polygon, err := parseLocations(value)
+		if err != nil {
+			return avoid, nil, err
+		}
+		if len(polygon) < 3 {
+			return avoid, nil, fmt.Errorf("un polígono requiere al menos 3 puntos")
+		}
+		avoid.Polygons = append(avoid.Polygons, polygon)
+	}
+
+	var reports map[string]float64
+	for _, item := range strings.Split(r.FormValue("avoid_reports"), ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		reportType := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || reportType == "" {
+			return avoid, nil, fmt.Errorf("reporte a evitar inválido %q", item)
+		}
+		meters, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || meters <= 0 || meters > services.MaxAvoidRadius*1000 {
+			return avoid, nil, fmt.Errorf("radio inválido %q (máximo %.0f m)", item, services.MaxAvoidRadius*1000)
+		}
+		if reports == nil {
+			reports = make(map[string]float64)
+		}
+		reports[reportType] = meters / 1000
+	}
+
+	return avoid, reports, nil
+}
+
+// MatrixHandler retorna en JSON los tiempos (segundos) y distancias (km) de viaje entre
+// cada origen y cada destino ("lat,lng;lat,lng"). Sin destinos se usan los orígenes
+func (h *APIHandler) MatrixHandler(w http.ResponseWriter, r *http.Request) {
+	origins, err := parseLocations(r.FormValue("origins"))
+	if err != nil || len(origins) == 0 {
+		httpError(w, r, "Orígenes inválidos", http.StatusBadRequest)
+		return
+	}
+	destinations, err := parseLocations(r.FormValue("destinations"))
+	if err != nil {
+		httpError(w, r, "Destinos inválidos", http.StatusBadRequest)
+		return
+	}
+	if len(origins) > services.MaxMatrixLocations || len(destinations) > services.MaxMatrixLocations {
+		httpError(w, r, fmt.Sprintf("Máximo %d orígenes y %d destinos", services.MaxMatrixLocations, services.MaxMatrixLocations), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := routing.ProfileByName(r.FormValue("profile"))
+	if err != nil {
+		httpError(w, r, "Perfil inválido. Opciones: "+strings.Join(routing.ProfileNames(), ", "), http.StatusBadRequest)
+		return
+	}
+	avoid, avoidReports, err := parseAvoid(r)
+	if err != nil {
+		httpError(w, r, "Restricciones inválidas: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	matrix, err := h.routingService.Matrix(services.MatrixRequest{
+		Origins:      origins,
+		Destinations: destinations,
+		Profile:      profile.Name,
+		Avoid:        avoid,
+		AvoidReports: avoidReports,
+	})
+	switch {
+	case errors.Is(err, routing.ErrNoGraph):
+		httpError(w, r, "Red vial no cargada: la matriz requiere un extracto OSM", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		httpError(w, r, "Error calculando matriz", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, matrix)
+}
+
+// MatchRequest cuerpo JSON de una solicitud de ajuste de traza
+type MatchRequest struct {
+	Profile string              `json:"profile"`
+	Points  []models.TracePoint `json:"points"`
+}
+
+// MatchTraceHandler ajusta a la red vial una traza GPS recibida en JSON y retorna el
+// camino recorrido con los tiempos de cada tramo
+func (h *APIHandler) MatchTraceHandler(w http.ResponseWriter, r *http.Request) {
+	var req MatchRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxMatchRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, r, fmt.Sprintf("Traza demasiado grande: máximo %d puntos", services.MaxTracePoints), http.StatusRequestEntityTooLarge)
+			return
+		}
+		httpError(w, r, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if len(req.Points) < 2 {
+		httpError(w, r, "La traza requiere al menos 2 puntos", http.StatusBadRequest)
+		return
+	}
+	if len(req.Points) > services.MaxTracePoints {
+		httpError(w, r, fmt.Sprintf("Máximo %d puntos por traza", services.MaxTracePoints), http.StatusBadRequest)
+		return
+	}
+	for _, p := range req.Points {
+		if !utils.ValidateCoordinates(p.Lat, p.Lng) {
+			httpError(w, r, "Coordenadas inválidas", http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := routing.ProfileByName(req.Profile); err != nil {
+		httpError(w, r, "Perfil inválido. Opciones: "+strings.Join(routing.ProfileNames(), ", "), http.StatusBadRequest)
+		return
+	}
+
+	matched, err := h.routingService.MatchTrace(req.Points, req.Profile)
+	switch {
+	case errors.Is(err, routing.ErrNoGraph):
+		httpError(w, r, "Red vial no cargada: el ajuste de trazas requiere un extracto OSM", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, routing.ErrNoMatch):
+		httpError(w, r, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		httpError(w, r, "Error ajustando traza", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, matched)
+}
+
+// IsochroneHandler retorna en GeoJSON las zonas alcanzables desde un punto
+// dentro de los tiempos indicados en minutes ("5,10,15" por defecto)
+func (h *APIHandler) IsochroneHandler(w http.ResponseWriter, r *http.Request) {
+	lat, errLat := strconv.ParseFloat(r.FormValue("lat"), 64)
+	lng, errLng := strconv.ParseFloat(r.FormValue("lng"), 64)
+	if errLat != nil || errLng != nil || !utils.ValidateCoordinates(lat, lng) {
+		httpError(w, r, "Coordenadas inválidas", http.StatusBadRequest)
+		return
+	}
+
+	var minutes []int
+	for _, value := range strings.Split(r.FormValue("minutes"), ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		m, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || m <= 0 || m > services.MaxIsochroneMinutes {
+			httpError(w, r, fmt.Sprintf("Tiempos inválidos (1-%d minutos)", services.MaxIsochroneMinutes), http.StatusBadRequest)
+			return
+		}
+		minutes = append(minutes, m)
+	}
+
+	profile, err := routing.ProfileByName(r.FormValue("profile"))
+	if err != nil {
+		httpError(w, r, "Perfil inválido. Opciones: "+strings.Join(routing.ProfileNames(), ", "), http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.routingService.Isochrone(services.IsochroneRequest{
+		Center:  models.Location{Lat: lat, Lng: lng},
+		Minutes: minutes,
+		Profile: profile.Name,
+	})
+	switch {
+	case errors.Is(err, routing.ErrNoGraph):
+		httpError(w, r, "Red vial no cargada: las isócronas requieren un extracto OSM", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, routing.ErrNoSnap):
+		httpError(w, r, "No se pudo calcular la isócrona: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		httpError(w, r, "Error calculando isócrona", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONAs(w, http.StatusOK, "application/geo+json", collection)
+}
+
 // GeocodeHandler maneja la geocodificación de direcciones
 func (h *APIHandler) GeocodeHandler(w http.ResponseWriter, r *http.Request) {
 	address := r.URL.Query().Get("address")
 	if address == "" {
-		http.Error(w, "Parámetro 'address' requerido", http.StatusBadRequest)
+		httpError(w, r, "Parámetro 'address' requerido", http.StatusBadRequest)
 		return
 	}
 
@@ -175,32 +1068,17 @@ func (h *APIHandler) GeocodeHandler(w http.ResponseWriter, r *http.Request) {
 	url := fmt.Sprintf("https://nominatim.openstreetmap.org/search?format=json&q=%s&limit=1", address)
 	resp, err := http.Get(url)
 	if err != nil {
-		http.Error(w, "Error llamando a la API de geocodificación", http.StatusInternalServerError)
+		httpError(w, r, "Error llamando a la API de geocodificación", http.StatusInternalServerError)
 		return
 	}
 	defer resp.Body.Close()
 
 	var results []models.NominatimResponse
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		http.Error(w, "Error procesando respuesta de geocodificación", http.StatusInternalServerError)
+		httpError(w, r, "Error procesando respuesta de geocodificación", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(results)
 }
-
-// getReportIcon retorna el emoji correspondiente al tipo de reporte
-func getReportIcon(reportType string) string {
-	icons := map[string]string{
-		"accident": "🚗",
-		"police":   "👮",
-		"traffic":  "🚦",
-		"hazard":   "⚠️",
-	}
-	
-	if icon, exists := icons[reportType]; exists {
-		return icon
-	}
-	return "📍" // Icono por defecto
-}
\ No newline at end of file
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// APIVersionPrefix espacio de la API que siempre responde JSON
+const APIVersionPrefix = "/api/v1"
+
+// wantsJSON indica si el cliente pidió JSON: rutas bajo /api/v1 o un encabezado Accept
+// que prefiere application/json sobre HTML, como los que envían las apps y los scripts
+func wantsJSON(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, APIVersionPrefix+"/") {
+		return true
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			return true
+		case "text/html":
+			return false
+		}
+	}
+	return false
+}
+
+// writeJSON responde un valor como JSON con el código indicado
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	writeJSONAs(w, status, "application/json", v)
+}
+
+// writeJSONAs responde un valor serializado en JSON con un tipo de contenido propio,
+// como application/geo+json
+func writeJSONAs(w http.ResponseWriter, status int, contentType string, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error serializando respuesta: %v", err)
+	}
+}
+
+// httpError responde un error como {"error": "..."} si el cliente pidió JSON o como
+// texto plano en otro caso
+func httpError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if wantsJSON(r) {
+		writeJSON(w, status, map[string]string{"error": message})
+		return
+	}
+	http.Error(w, message, status)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"gowaze/services"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SessionHeader encabezado con el token de sesión que se entrega al registrar un
+// usuario, para los clientes que no guardan cookies
+const SessionHeader = "X-Session-Token"
+
+// startSession abre una sesión para un usuario y entrega su token como cookie y en
+// SessionHeader
+func startSession(w http.ResponseWriter, r *http.Request, sessions *services.SessionStore, userID int) error {
+	token, err := sessions.Create(userID)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     services.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(services.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set(SessionHeader, token)
+	return nil
+}
+
+// sessionUser identifica al usuario de una solicitud por su token de sesión, enviado
+// en el encabezado Authorization: Bearer o en la cookie services.SessionCookie
+func sessionUser(sessions *services.SessionStore, r *http.Request) (int, bool) {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return sessions.User(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if cookie, err := r.Cookie(services.SessionCookie); err == nil {
+		return sessions.User(cookie.Value)
+	}
+	return 0, false
+}
+
+// clientAddr dirección IP del cliente, con la que se limita la emisión de sesiones.
+// No se confía en X-Forwarded-For porque el cliente puede enviarlo a voluntad
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
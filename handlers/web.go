@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"gowaze/services"
 	"html/template"
 	"net/http"
 )
@@ -29,9 +30,13 @@ func (h *WebHandler) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	
 	data := struct {
-		Title string
+		Title       string
+		ReportTypes []*services.ReportType
+		Directions  []string
 	}{
-		Title: "GoWaze - Navegación en Tiempo Real",
+		Title:       "GoWaze - Navegación en Tiempo Real",
+		ReportTypes: services.ReportTypes(),
+		Directions:  services.Directions,
 	}
 
 	if err := h.template.Execute(w, data); err != nil {
@@ -133,13 +138,20 @@ const embeddedTemplate = `
                 <!-- Crear Reporte -->
                 <div class="card">
                     <h3>🚨 Nuevo Reporte</h3>
-                    <form hx-post="/api/reports" hx-target="#reports-container" hx-swap="innerHTML">
+                    <form hx-post="/api/reports" hx-target="#reports-container" hx-swap="innerHTML" hx-encoding="multipart/form-data">
                         <div class="form-group">
                             <select id="report-type" name="type" required>
-                                <option value="accident">🚗 Accidente</option>
-                                <option value="police">👮 Policía</option>
-                                <option value="traffic">🚦 Tráfico</option>
-                                <option value="hazard">⚠️ Peligro</option>
+                                {{range .ReportTypes}}<option value="{{.Name}}">{{.Icon}} {{.Label}}</option>
+                                {{end}}
+                            </select>
+                        </div>
+                        <div class="form-group">
+                            <select id="report-subtype" name="subtype">
+                                <option value="">Sin detalle</option>
+                                {{range .ReportTypes}}<optgroup label="{{.Label}}">
+                                    {{range .Subtypes}}<option value="{{.Name}}">{{.Label}}</option>{{end}}
+                                </optgroup>
+                                {{end}}
                             </select>
                         </div>
                         <input type="hidden" id="report-lat" name="lat" value="14.0818">
@@ -147,6 +159,9 @@ const embeddedTemplate = `
                         <div class="form-group">
                             <textarea id="description" name="description" rows="2" placeholder="Describe lo que está pasando..."></textarea>
                         </div>
+                        <div class="form-group">
+                            <input type="file" name="photos" accept="image/jpeg,image/png" multiple>
+                        </div>
                         <button type="submit" class="btn">📢 Reportar</button>
                     </form>
                     <small style="color: #666;">Tip: Click derecho en el mapa para establecer ubicación</small>
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"gowaze/models"
 	"gowaze/services"
 	"log"
 	"net/http"
@@ -11,17 +12,21 @@ import (
 // WebSocketHandler maneja las conexiones WebSocket
 type WebSocketHandler struct {
 	wsService *services.WebSocketService
+	sessions  *services.SessionStore
 }
 
 // NewWebSocketHandler crea una nueva instancia del handler WebSocket
-func NewWebSocketHandler(wsService *services.WebSocketService) *WebSocketHandler {
+func NewWebSocketHandler(wsService *services.WebSocketService, sessions *services.SessionStore) *WebSocketHandler {
 	return &WebSocketHandler{
 		wsService: wsService,
+		sessions:  sessions,
 	}
 }
 
-// HandleWebSocket maneja las conexiones WebSocket
+// HandleWebSocket maneja las conexiones WebSocket. La conexión queda asociada al
+// usuario de la sesión con que se abrió, si la hay
 func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, _ := sessionUser(h.sessions, r)
 	upgrader := h.wsService.GetUpgrader()
 
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -32,12 +37,12 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	defer conn.Close()
 
 	// Agregar cliente
-	h.wsService.AddClient(conn)
+	h.wsService.AddClient(conn, userID)
 	defer h.wsService.RemoveClient(conn)
 
 	// Escuchar mensajes del cliente
 	for {
-		var msg map[string]interface{}
+		var msg models.ClientMessage
 		err := conn.ReadJSON(&msg)
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
@@ -47,25 +52,49 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		}
 
 		// Procesar mensajes del cliente si es necesario
-		h.handleClientMessage(msg)
+		h.handleClientMessage(conn, msg)
 	}
 }
 
 // handleClientMessage procesa mensajes recibidos del cliente
-func (h *WebSocketHandler) handleClientMessage(msg map[string]interface{}) {
-	msgType, ok := msg["type"].(string)
-	if !ok {
-		return
-	}
-
-	switch msgType {
+func (h *WebSocketHandler) handleClientMessage(conn *websocket.Conn, msg models.ClientMessage) {
+	switch msg.Type {
 	case "ping":
 		// Responder a ping para mantener conexión viva
 		log.Printf("📡 Ping recibido de cliente WebSocket")
 	case "request_stats":
 		// Cliente solicita estadísticas actualizadas
 		h.wsService.BroadcastStats()
+	case "nav_start":
+		// Cliente inicia un viaje desde su posición actual
+		if msg.To == nil {
+			log.Printf("📨 nav_start sin destino")
+			return
+		}
+		h.wsService.StartNavigation(conn, services.RouteRequest{
+			From:      models.Location{Lat: msg.Lat, Lng: msg.Lng},
+			To:        *msg.To,
+			Waypoints: msg.Waypoints,
+			Profile:   msg.Profile,
+			Language:  msg.Lang,
+		})
+	case "nav_position":
+		// Nueva posición del conductor durante el viaje
+		h.wsService.UpdateNavigation(conn, models.Location{Lat: msg.Lat, Lng: msg.Lng})
+	case "nav_stop":
+		h.wsService.StopNavigation(conn)
+	case "report_upvote", "report_downvote":
+		// Confirmación "sigue ahí" o descarte "ya no está" del usuario de la conexión
+		if msg.ReportID == 0 {
+			log.Printf("📨 %s sin report_id", msg.Type)
+			return
+		}
+		vote := services.VoteUp
+		if msg.Type == "report_downvote" {
+			vote = services.VoteDown
+		}
+		h.wsService.VoteReport(conn, msg.ReportID, vote)
 	default:
-		log.Printf("📨 Mensaje WebSocket desconocido: %s", msgType)
+		log.Printf("📨 Mensaje WebSocket desconocido: %s", msg.Type)
 	}
 }
@@ -1,35 +1,97 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"gowaze/handlers"
+	"gowaze/routing"
 	"gowaze/services"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
+	osmFile := flag.String("osm", "", "Extracto OpenStreetMap (.osm, .osm.gz o .pbf) para construir la red vial")
+	storageKind := flag.String("storage", "memory", "Almacenamiento de datos: memory o file")
+	dataDir := flag.String("data", "data", "Directorio de datos del almacenamiento file (WAL e instantáneas)")
+	blobKind := flag.String("blobs", "disk", "Almacén de fotos de los reportes: memory o disk (en <data>/photos)")
+	lifetimes := flag.String("lifetimes", "", "Vida de los reportes por tipo o tipo/subtipo, p. ej. police=1h,hazard/pothole=12h")
+	flag.Parse()
+
+	if err := services.SetReportLifetimes(*lifetimes); err != nil {
+		log.Fatalf("Error en -lifetimes: %v", err)
+	}
+
+	// Cargar red vial offline
+	roadGraph := routing.NewGraph()
+	if *osmFile != "" {
+		start := time.Now()
+		graph, err := routing.LoadOSM(*osmFile)
+		if err != nil {
+			log.Fatalf("Error cargando red vial: %v", err)
+		}
+		roadGraph = graph
+		log.Printf("🛣️  Red vial cargada desde %s: %d nodos, %d tramos (%v)",
+			*osmFile, len(roadGraph.Nodes), len(roadGraph.Edges), time.Since(start).Round(time.Millisecond))
+	} else {
+		log.Println("⚠️  Sin extracto OSM (-osm): las rutas se estimarán en línea recta")
+	}
+
 	// Inicializar servicios
-	storage := services.NewStorage()
-	trafficService := services.NewTrafficService(storage)
-	wsService := services.NewWebSocketService(storage)
+	storage, err := services.OpenStorage(*storageKind, *dataDir)
+	if err != nil {
+		log.Fatalf("Error abriendo almacenamiento: %v", err)
+	}
+	blobStore, err := services.OpenBlobStore(*blobKind, filepath.Join(*dataDir, "photos"))
+	if err != nil {
+		log.Fatalf("Error abriendo almacén de fotos: %v", err)
+	}
+	routingService := services.NewRoutingService(storage, roadGraph)
+	trafficService := services.NewTrafficService(storage, routingService.History())
+	navigationService := services.NewNavigationService(routingService)
+	wsService := services.NewWebSocketService(storage, navigationService)
+	photoService := services.NewPhotoService(storage, blobStore)
+	sessions := services.NewSessionStore()
 
 	// Inicializar handlers
-	apiHandler := handlers.NewAPIHandler(storage, wsService)
+	apiHandler := handlers.NewAPIHandler(storage, wsService, routingService, photoService, sessions)
 	webHandler := handlers.NewWebHandler()
-	wsHandler := handlers.NewWebSocketHandler(wsService)
+	wsHandler := handlers.NewWebSocketHandler(wsService, sessions)
 
 	// Datos de ejemplo iniciales
-	storage.InitSampleData()
+	if err := storage.InitSampleData(); err != nil {
+		log.Printf("Error inicializando datos de ejemplo: %v", err)
+	}
 
 	// Iniciar servicios en background
 	go trafficService.Start()
 	go wsService.HandleBroadcast()
 	go storage.StartCleanup()
+	go photoService.StartCleanup()
+	go sessions.StartCleanup()
+	go routingService.StartHistorySaves()
+
+	// Guardar el historial de velocidades y cerrar el almacenamiento al detener el servidor
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		if err := routingService.SaveHistory(); err != nil {
+			log.Printf("Error guardando historial de velocidades: %v", err)
+		}
+		if err := storage.Close(); err != nil {
+			log.Printf("Error cerrando almacenamiento: %v", err)
+		}
+		os.Exit(0)
+	}()
 
 	// Configurar rutas
 	r := mux.NewRouter()
@@ -41,12 +103,28 @@ func main() {
 	// Rutas frontend
 	r.HandleFunc("/", webHandler.HomeHandler).Methods("GET")
 
-	// API Routes
-	r.HandleFunc("/api/users", apiHandler.CreateUserHandler).Methods("POST")
-	r.HandleFunc("/api/reports", apiHandler.CreateReportHandler).Methods("POST")
-	r.HandleFunc("/api/reports", apiHandler.GetReportsHandler).Methods("GET")
-	r.HandleFunc("/api/routes", apiHandler.CalculateRouteHandler).Methods("POST")
-	r.HandleFunc("/api/geocode", apiHandler.GeocodeHandler).Methods("GET")
+	// API Routes: /api responde fragmentos HTML para HTMX (o JSON con Accept:
+	// application/json) y /api/v1 siempre JSON
+	for _, prefix := range []string{"/api", handlers.APIVersionPrefix} {
+		api := r.PathPrefix(prefix).Subrouter()
+		api.HandleFunc("/users", apiHandler.CreateUserHandler).Methods("POST")
+		api.HandleFunc("/reports", apiHandler.CreateReportHandler).Methods("POST")
+		api.HandleFunc("/reports", apiHandler.GetReportsHandler).Methods("GET")
+		api.HandleFunc("/report-types", apiHandler.ReportTypesHandler).Methods("GET")
+		api.HandleFunc("/reports/{id:[0-9]+}/upvote", apiHandler.UpvoteReportHandler).Methods("POST")
+		api.HandleFunc("/reports/{id:[0-9]+}/downvote", apiHandler.DownvoteReportHandler).Methods("POST")
+		api.HandleFunc("/routes", apiHandler.CalculateRouteHandler).Methods("POST")
+		api.HandleFunc("/routes/export", apiHandler.ExportRouteHandler).Methods("GET", "POST")
+		api.HandleFunc("/trips", apiHandler.GetTripsHandler).Methods("GET")
+		api.HandleFunc("/trips/{id:[0-9]+}/export", apiHandler.ExportTripHandler).Methods("GET")
+		api.HandleFunc("/matrix", apiHandler.MatrixHandler).Methods("GET", "POST")
+		api.HandleFunc("/match", apiHandler.MatchTraceHandler).Methods("POST")
+		api.HandleFunc("/isochrone", apiHandler.IsochroneHandler).Methods("GET")
+		api.HandleFunc("/geocode", apiHandler.GeocodeHandler).Methods("GET")
+	}
+
+	// Fotos de los reportes
+	r.HandleFunc(services.PhotosPath+"{key}", apiHandler.PhotoHandler).Methods("GET")
 
 	// WebSocket
 	r.HandleFunc("/ws", wsHandler.HandleWebSocket)
@@ -69,7 +147,7 @@ func main() {
 	fmt.Println("🎯 Ubicación por defecto: San Pedro Sula, Honduras")
 	fmt.Println("📊 Características:")
 	fmt.Println("   • Mapas interactivos reales")
-	fmt.Println("   • Cálculo de rutas con Leaflet Routing")
+	fmt.Println("   • Cálculo de rutas offline sobre red vial OSM")
 	fmt.Println("   • Búsqueda de lugares")
 	fmt.Println("   • Reportes en tiempo real")
 	fmt.Println("   • Geolocalización GPS")
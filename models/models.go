@@ -13,24 +13,90 @@ type User struct {
 
 // Report representa un reporte de tráfico, accidente, etc.
 type Report struct {
-	ID          int       `json:"id"`
-	Type        string    `json:"type"` // "accident", "police", "traffic", "hazard"
-	Lat         float64   `json:"lat"`
-	Lng         float64   `json:"lng"`
-	Description string    `json:"description"`
-	UserID      int       `json:"user_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	Votes       int       `json:"votes"`
+	ID           int       `json:"id"`
+	Type         string    `json:"type"`                // "accident", "police", "traffic", "hazard", "closure"
+	Subtype      string    `json:"subtype,omitempty"`   // p. ej. "pothole" o "flooding" para "hazard"
+	Severity     int       `json:"severity"`            // gravedad de 1 (leve) a 5 (crítica)
+	Direction    string    `json:"direction,omitempty"` // sentido afectado: "N", "NE", ..., "NW" o "both"
+	Lanes        []int     `json:"lanes,omitempty"`     // carriles afectados, numerados desde la izquierda
+	Lat          float64   `json:"lat"`
+	Lng          float64   `json:"lng"`
+	Description  string    `json:"description"`
+	UserID       int       `json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Votes        int       `json:"votes"`                  // votos "sigue ahí" menos votos "ya no está"
+	Upvotes      int       `json:"upvotes"`                // confirmaciones, incluida la del autor
+	Downvotes    int       `json:"downvotes"`              // votos "ya no está"
+	Dismissals   int       `json:"dismissals"`             // votos "ya no está" desde la última confirmación
+	ConfirmedAt  time.Time `json:"confirmed_at"`           // última confirmación
+	ExpiresAt    time.Time `json:"expires_at"`             // vencimiento según la vida de su tipo
+	Confidence   float64   `json:"confidence"`             // confianza (0-1) en que siga vigente
+	MergedIDs    []int     `json:"merged_ids,omitempty"`   // reportes duplicados agrupados en este incidente
+	Descriptions []string  `json:"descriptions,omitempty"` // descripciones de todos los reportes agrupados
+	Photos       []Photo   `json:"photos,omitempty"`       // fotos adjuntas, sin metadatos EXIF
+}
+
+// Photo representa una foto adjunta a un reporte
+type Photo struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Size         int    `json:"size"` // en bytes, ya procesada
 }
 
 // Route representa una ruta calculada
 type Route struct {
-	ID       int        `json:"id"`
-	From     Location   `json:"from"`
-	To       Location   `json:"to"`
-	Points   []Location `json:"points"`
-	Distance float64    `json:"distance"`
-	Duration int        `json:"duration"` // en minutos
+	ID           int                `json:"id"`
+	From         Location           `json:"from"`
+	To           Location           `json:"to"`
+	Points       []Location         `json:"points,omitempty"`
+	Polyline     string             `json:"polyline,omitempty"`           // polilínea codificada de Google
+	Precision    int                `json:"polyline_precision,omitempty"` // 5 o 6 decimales
+	Geometry     *Geometry          `json:"geometry,omitempty"`           // GeoJSON LineString
+	Distance     float64            `json:"distance"`
+	Duration     int                `json:"duration"`  // en minutos
+	Delay        int                `json:"delay"`     // demora por tráfico en minutos
+	DepartAt     time.Time          `json:"depart_at"` // hora de salida
+	ArriveAt     time.Time          `json:"arrive_at"` // hora estimada de llegada
+	Profile      string             `json:"profile,omitempty"`
+	Congestion   *CongestionSummary `json:"congestion,omitempty"`
+	Alternatives []Route            `json:"alternatives,omitempty"`
+	Waypoints    []Location         `json:"waypoints,omitempty"` // paradas intermedias en orden de visita
+	Legs         []RouteLeg         `json:"legs,omitempty"`
+	Steps        []RouteStep        `json:"steps,omitempty"`
+}
+
+// RouteStep representa una maniobra de las instrucciones paso a paso
+type RouteStep struct {
+	Maneuver    string   `json:"maneuver"`           // "depart", "turn", "continue", "keep", "roundabout", "waypoint", "arrive"
+	Modifier    string   `json:"modifier,omitempty"` // "left", "slight_right", "sharp_left", "straight", "uturn", ...
+	Exit        int      `json:"exit,omitempty"`     // salida de rotonda
+	Street      string   `json:"street"`
+	Distance    float64  `json:"distance"` // km hasta la siguiente maniobra
+	Duration    int      `json:"duration"` // segundos hasta la siguiente maniobra
+	Bearing     float64  `json:"bearing"`  // rumbo de salida en grados
+	Direction   string   `json:"direction"`
+	Location    Location `json:"location"`
+	Instruction string   `json:"instruction"`
+}
+
+// RouteLeg representa un tramo de una ruta entre dos paradas consecutivas
+type RouteLeg struct {
+	From     Location `json:"from"`
+	To       Location `json:"to"`
+	Distance float64  `json:"distance"`
+	Duration int      `json:"duration"` // en minutos
+}
+
+// CongestionSummary resume el tráfico a lo largo de una ruta
+type CongestionSummary struct {
+	Level  string  `json:"level"`  // "low", "medium", "high"
+	Low    float64 `json:"low"`    // km con tráfico fluido
+	Medium float64 `json:"medium"` // km con tráfico moderado
+	High   float64 `json:"high"`   // km con tráfico pesado
 }
 
 // Location representa una coordenada geográfica
@@ -57,11 +123,114 @@ type NominatimResponse struct {
 
 // WebSocketMessage mensaje para comunicación WebSocket
 type WebSocketMessage struct {
-	Type         string      `json:"type"`
-	UsersOnline  int         `json:"users_online,omitempty"`
-	TotalReports int         `json:"total_reports,omitempty"`
-	TrafficPoints int        `json:"traffic_points,omitempty"`
-	Report       *Report     `json:"report,omitempty"`
-	Reports      []*Report   `json:"reports,omitempty"`
-	Data         interface{} `json:"data,omitempty"`
-}
\ No newline at end of file
+	Type          string            `json:"type"`
+	UsersOnline   int               `json:"users_online,omitempty"`
+	TotalReports  int               `json:"total_reports,omitempty"`
+	TrafficPoints int               `json:"traffic_points,omitempty"`
+	Report        *Report           `json:"report,omitempty"`
+	Reports       []*Report         `json:"reports,omitempty"`
+	Navigation    *NavigationUpdate `json:"navigation,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Data          interface{}       `json:"data,omitempty"`
+}
+
+// ClientMessage mensaje recibido de un cliente WebSocket
+type ClientMessage struct {
+	Type      string     `json:"type"`
+	Lat       float64    `json:"lat,omitempty"`
+	Lng       float64    `json:"lng,omitempty"`
+	To        *Location  `json:"to,omitempty"`
+	Waypoints []Location `json:"waypoints,omitempty"`
+	Profile   string     `json:"profile,omitempty"`
+	Lang      string     `json:"lang,omitempty"`
+	ReportID  int        `json:"report_id,omitempty"`
+}
+
+// NavigationUpdate estado de una sesión de navegación enviado al cliente
+type NavigationUpdate struct {
+	SessionID         string     `json:"session_id"`
+	Status            string     `json:"status"`             // "active", "off_route", "rerouted", "arrived"
+	Reason            string     `json:"reason,omitempty"`   // motivo del recálculo: "deviation", "report"
+	Position          Location   `json:"position"`           // posición ajustada a la ruta
+	Traveled          float64    `json:"traveled"`           // km recorridos sobre la ruta actual
+	Remaining         float64    `json:"remaining"`          // km restantes
+	RemainingDuration int        `json:"remaining_duration"` // minutos restantes
+	ETA               time.Time  `json:"eta"`
+	NextStep          *RouteStep `json:"next_step,omitempty"`
+	NextStepDistance  float64    `json:"next_step_distance"` // km hasta la próxima maniobra
+	TripID            int        `json:"trip_id,omitempty"`  // viaje registrado al terminar
+	Route             *Route     `json:"route,omitempty"`    // solo al iniciar o recalcular
+}
+
+// Matrix matriz de tiempos y distancias de viaje entre orígenes y destinos.
+// Las celdas sin ruta son null
+type Matrix struct {
+	Origins      []Location   `json:"origins"`
+	Destinations []Location   `json:"destinations"`
+	Durations    [][]*float64 `json:"durations"` // en segundos
+	Distances    [][]*float64 `json:"distances"` // en km
+	Profile      string       `json:"profile"`
+}
+
+// TracePoint posición GPS registrada en un instante
+type TracePoint struct {
+	Lat       float64   `json:"lat"`
+	Lng       float64   `json:"lng"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MatchedSegment porción de una vía recorrida por una traza ajustada a la red
+type MatchedSegment struct {
+	Edge      int       `json:"edge"`
+	WayID     int64     `json:"way_id"`
+	Street    string    `json:"street"`
+	From      Location  `json:"from"`
+	To        Location  `json:"to"`
+	Distance  float64   `json:"distance"` // en km
+	Duration  float64   `json:"duration"` // en segundos
+	Speed     float64   `json:"speed"`    // velocidad observada en km/h
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// MatchedTrace traza GPS ajustada a la red vial
+type MatchedTrace struct {
+	Points   []*Location      `json:"points"` // posición ajustada de cada punto de entrada, null si se descartó
+	Path     []Location       `json:"path"`   // geometría recorrida
+	Segments []MatchedSegment `json:"segments"`
+	Distance float64          `json:"distance"` // en km
+	Duration float64          `json:"duration"` // en segundos
+}
+
+// Trip viaje registrado durante una sesión de navegación
+type Trip struct {
+	ID        int          `json:"id"`
+	SessionID string       `json:"session_id"`
+	Profile   string       `json:"profile"`
+	From      Location     `json:"from"`
+	To        Location     `json:"to"`
+	Points    []TracePoint `json:"points,omitempty"`
+	Distance  float64      `json:"distance"` // km recorridos según la traza
+	Arrived   bool         `json:"arrived"`
+	StartedAt time.Time    `json:"started_at"`
+	EndedAt   time.Time    `json:"ended_at"`
+}
+
+// FeatureCollection colección de elementos GeoJSON (RFC 7946)
+type FeatureCollection struct {
+	Type     string    `json:"type"` // "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+// Feature elemento GeoJSON con geometría y propiedades
+type Feature struct {
+	Type       string                 `json:"type"` // "Feature"
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry geometría GeoJSON. Las coordenadas van en orden [lng, lat]
+type Geometry struct {
+	Type        string      `json:"type"` // "Point", "LineString", "Polygon"
+	Coordinates interface{} `json:"coordinates"`
+}
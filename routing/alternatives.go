@@ -0,0 +1,94 @@
+package routing
+
+import (
+	"gowaze/models"
+	"math"
+)
+
+const (
+	// alternativePenalty multiplicador de costo para tramos ya usados por una ruta
+	alternativePenalty = 1.4
+	// alternativeMaxShare proporción máxima de distancia compartida con otra ruta
+	alternativeMaxShare = 0.7
+	// alternativeMaxStretch duración máxima de una alternativa relativa a la mejor ruta
+	alternativeMaxStretch = 1.5
+)
+
+// Alternatives calcula la mejor ruta y hasta n alternativas significativamente
+// distintas usando el método de penalización. El primer camino es siempre el óptimo
+func (r *Router) Alternatives(from, to models.Location, weighting Weighting, n int) ([]*Path, error) {
+	start, end, err := r.snapPair(from, to, weighting)
+	if err != nil {
+		return nil, err
+	}
+
+	best, err := r.shortestPath(start, end, weighting, nil)
+	if err != nil {
+		return nil, err
+	}
+	paths := []*Path{best}
+
+	penalties := make(map[int]float64)
+	penalize := func(path *Path) {
+		for _, seg := range path.Segments {
+			if p, ok := penalties[seg.Edge]; ok {
+				penalties[seg.Edge] = p * alternativePenalty
+			} else {
+				penalties[seg.Edge] = alternativePenalty
+			}
+		}
+	}
+	penalized := func(edge int, elapsed float64) float64 {
+		if p, ok := penalties[edge]; ok {
+			return weighting(edge, elapsed) * p
+		}
+		return weighting(edge, elapsed)
+	}
+
+	penalize(best)
+	for attempt := 0; attempt < n*3 && len(paths) <= n; attempt++ {
+		candidate, err := r.shortestPath(start, end, penalized, weighting)
+		if err != nil {
+			break
+		}
+		penalize(candidate)
+
+		if candidate.Duration > best.Duration*alternativeMaxStretch {
+			break // las siguientes serán aún más lentas
+		}
+		if isDistinct(candidate, paths) {
+			paths = append(paths, candidate)
+		}
+	}
+
+	return paths, nil
+}
+
+// isDistinct indica si un camino comparte poca distancia con los caminos aceptados
+func isDistinct(candidate *Path, accepted []*Path) bool {
+	if candidate.Distance <= 0 {
+		return false
+	}
+	for _, other := range accepted {
+		if sharedDistance(candidate, other)/math.Min(candidate.Distance, other.Distance) > alternativeMaxShare {
+			return false
+		}
+	}
+	return true
+}
+
+// sharedDistance distancia en km que recorren ambos caminos por los mismos tramos
+func sharedDistance(a, b *Path) float64 {
+	edges := make(map[int]bool, len(b.Segments))
+	for _, seg := range b.Segments {
+		edges[seg.Edge] = true
+	}
+
+	shared := 0.0
+	for _, seg := range a.Segments {
+		if edges[seg.Edge] {
+			shared += seg.Distance
+		}
+	}
+	return shared
+}
@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"gowaze/models"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAlternatives(t *testing.T) {
+	// De A a C la mejor ruta es Norte (100 s); rodear por Sur toma ~139 s, dentro del
+	// estiramiento máximo, y no comparte tramos con la mejor. Las combinaciones por el
+	// tramo central quedan siempre más penalizadas que alguna de las dos, así que no
+	// hay una tercera alternativa
+	a := models.Location{Lat: 0, Lng: 0}
+	c := models.Location{Lat: 0, Lng: 0.02}
+
+	tests := []struct {
+		name      string
+		from, to  models.Location
+		n         int
+		wantNames [][]string
+	}{
+		{"sin alternativas", a, c, 0, [][]string{{"Norte"}}},
+		{"una alternativa", a, c, 1, [][]string{{"Norte"}, {"Oeste", "Sur", "Este"}}},
+		{"más alternativas de las que existen", a, c, 3, [][]string{{"Norte"}, {"Oeste", "Sur", "Este"}}},
+		// Entre puntos interiores de Norte, rodear por Sur toma más de 1.5 veces la mejor ruta
+		{
+			name:      "alternativa demasiado lenta",
+			from:      models.Location{Lat: 0, Lng: 0.001},
+			to:        models.Location{Lat: 0, Lng: 0.019},
+			n:         1,
+			wantNames: [][]string{{"Norte"}},
+		},
+	}
+
+	g := ladderGraph()
+	router := NewRouter(g)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best, err := router.Route(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			paths, err := router.Alternatives(tt.from, tt.to, router.FreeFlow(), tt.n)
+			if err != nil {
+				t.Fatalf("Alternatives: %v", err)
+			}
+			if len(paths) != len(tt.wantNames) {
+				t.Fatalf("Alternatives retornó %d caminos, esperados %d", len(paths), len(tt.wantNames))
+			}
+			for i, want := range tt.wantNames {
+				if got := roadNames(g, paths[i]); !reflect.DeepEqual(got, want) {
+					t.Errorf("camino %d por %v, esperado %v", i, got, want)
+				}
+			}
+
+			if math.Abs(paths[0].Duration-best.Duration) > 1e-9 || math.Abs(paths[0].Distance-best.Distance) > 1e-9 {
+				t.Errorf("el primer camino (%.2f s) no es el óptimo (%.2f s)", paths[0].Duration, best.Duration)
+			}
+			for i, path := range paths {
+				if path.Duration > best.Duration*alternativeMaxStretch {
+					t.Errorf("camino %d toma %.2f s, más de %.1f veces la mejor ruta", i, path.Duration, alternativeMaxStretch)
+				}
+				for j := 0; j < i; j++ {
+					if share := sharedDistance(path, paths[j]) / math.Min(path.Distance, paths[j].Distance); share > alternativeMaxShare {
+						t.Errorf("caminos %d y %d comparten %.0f%% de su recorrido", j, i, share*100)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"gowaze/models"
+	"math"
+)
+
+// Avoid tramos y zonas que una ruta debe evitar
+type Avoid struct {
+	Tolls    bool                `json:"tolls"`
+	Unpaved  bool                `json:"unpaved"`
+	Polygons [][]models.Location `json:"polygons,omitempty"`
+	Circles  []Circle            `json:"circles,omitempty"`
+}
+
+// Circle zona circular a evitar
+type Circle struct {
+	Center models.Location `json:"center"`
+	Radius float64         `json:"radius"` // en km
+}
+
+// Empty indica si no hay restricciones
+func (a *Avoid) Empty() bool {
+	return a == nil || !a.Tolls && !a.Unpaved && len(a.Polygons) == 0 && len(a.Circles) == 0
+}
+
+// Avoiding envuelve un peso para que los tramos evitados sean intransitables.
+// Las zonas se resuelven una sola vez a un conjunto de tramos bloqueados
+func (r *Router) Avoiding(weighting Weighting, avoid *Avoid) Weighting {
+	if avoid.Empty() {
+		return weighting
+	}
+
+	blocked := r.blockedEdges(avoid)
+	return func(edge int, elapsed float64) float64 {
+		e := &r.graph.Edges[edge]
+		if blocked[edge] || avoid.Tolls && e.Toll || avoid.Unpaved && e.Unpaved {
+			return math.Inf(1)
+		}
+		return weighting(edge, elapsed)
+	}
+}
+
+// blockedEdges tramos que pasan por alguna de las zonas a evitar
+func (r *Router) blockedEdges(avoid *Avoid) map[int]bool {
+	g := r.graph
+	blocked := make(map[int]bool)
+
+	for _, c := range avoid.Circles {
+		for _, snap := range g.Nearby(c.Center.Lat, c.Center.Lng, c.Radius) {
+			blocked[snap.Edge] = true
+		}
+	}
+
+	for _, polygon := range avoid.Polygons {
+		if len(polygon) < 3 {
+			continue
+		}
+		minLat, minLng, maxLat, maxLng := polygonBounds(polygon)
+		for i, e := range g.Edges {
+			a, b := g.Nodes[e.From], g.Nodes[e.To]
+			if math.Max(a.Lat, b.Lat) < minLat || math.Min(a.Lat, b.Lat) > maxLat ||
+				math.Max(a.Lng, b.Lng) < minLng || math.Min(a.Lng, b.Lng) > maxLng {
+				continue
+			}
+			if segmentInPolygon(a, b, polygon) {
+				blocked[i] = true
+			}
+		}
+	}
+
+	return blocked
+}
+
+// polygonBounds rectángulo envolvente de un polígono
+func polygonBounds(polygon []models.Location) (minLat, minLng, maxLat, maxLng float64) {
+	minLat, minLng = math.Inf(1), math.Inf(1)
+	maxLat, maxLng = math.Inf(-1), math.Inf(-1)
+	for _, p := range polygon {
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+		minLng, maxLng = math.Min(minLng, p.Lng), math.Max(maxLng, p.Lng)
+	}
+	return
+}
+
+// segmentInPolygon indica si un tramo tiene algún extremo dentro del polígono o lo cruza
+func segmentInPolygon(a, b Node, polygon []models.Location) bool {
+	if pointInPolygon(a.Lat, a.Lng, polygon) || pointInPolygon(b.Lat, b.Lng, polygon) {
+		return true
+	}
+	for i := range polygon {
+		p, q := polygon[i], polygon[(i+1)%len(polygon)]
+		if segmentsIntersect(a.Lng, a.Lat, b.Lng, b.Lat, p.Lng, p.Lat, q.Lng, q.Lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// pointInPolygon prueba de inclusión por trazado de rayos
+func pointInPolygon(lat, lng float64, polygon []models.Location) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		pi, pj := polygon[i], polygon[j]
+		if (pi.Lat > lat) != (pj.Lat > lat) &&
+			lng < (pj.Lng-pi.Lng)*(lat-pi.Lat)/(pj.Lat-pi.Lat)+pi.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// segmentsIntersect indica si los segmentos (x1,y1)-(x2,y2) y (x3,y3)-(x4,y4) se cruzan
+func segmentsIntersect(x1, y1, x2, y2, x3, y3, x4, y4 float64) bool {
+	d1 := orientation(x3, y3, x4, y4, x1, y1)
+	d2 := orientation(x3, y3, x4, y4, x2, y2)
+	d3 := orientation(x1, y1, x2, y2, x3, y3)
+	d4 := orientation(x1, y1, x2, y2, x4, y4)
+	return (d1 > 0) != (d2 > 0) && (d3 > 0) != (d4 > 0) && d1 != 0 && d2 != 0 && d3 != 0 && d4 != 0
+}
+
+// orientation producto cruz de (b-a) x (c-a)
+func orientation(ax, ay, bx, by, cx, cy float64) float64 {
+	return (bx-ax)*(cy-ay) - (by-ay)*(cx-ax)
+}
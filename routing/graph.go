@@ -0,0 +1,111 @@
+package routing
+
+import (
+	"gowaze/utils"
+)
+
+// Node representa una intersección o punto de forma de la red vial
+type Node struct {
+	ID  int64   `json:"id"`
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Edge representa un tramo dirigido entre dos nodos de la red vial
+type Edge struct {
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+	Length float64   `json:"length"` // en km
+	Speed  float64   `json:"speed"`  // velocidad de flujo libre en km/h
+	Name   string    `json:"name"`
+	Class  RoadClass `json:"class"`
+	WayID  int64     `json:"way_id"`
+
+	Roundabout bool       `json:"roundabout"`
+	Access     AccessMask `json:"access"`
+	MaxHeight  float64    `json:"max_height,omitempty"` // en metros
+	MaxWeight  float64    `json:"max_weight,omitempty"` // en toneladas
+	Toll       bool       `json:"toll,omitempty"`
+	Unpaved    bool       `json:"unpaved,omitempty"`
+}
+
+// Graph es un grafo dirigido de la red vial
+type Graph struct {
+	Nodes    []Node
+	Edges    []Edge
+	out      [][]int
+	index    map[int64]int
+	grid     *edgeGrid
+	maxSpeed float64
+}
+
+// NewGraph crea un grafo vacío
+func NewGraph() *Graph {
+	return &Graph{
+		index: make(map[int64]int),
+	}
+}
+
+// AddNode agrega un nodo y retorna su índice. Si el ID ya existe retorna el índice existente
+func (g *Graph) AddNode(id int64, lat, lng float64) int {
+	if idx, ok := g.index[id]; ok {
+		return idx
+	}
+
+	idx := len(g.Nodes)
+	g.Nodes = append(g.Nodes, Node{ID: id, Lat: lat, Lng: lng})
+	g.out = append(g.out, nil)
+	g.index[id] = idx
+	return idx
+}
+
+// NodeIndex retorna el índice interno de un nodo a partir de su ID
+func (g *Graph) NodeIndex(id int64) (int, bool) {
+	idx, ok := g.index[id]
+	return idx, ok
+}
+
+// AddEdge agrega un tramo dirigido y retorna su índice. Si no trae longitud se calcula con haversine
+func (g *Graph) AddEdge(e Edge) int {
+	if e.Length == 0 {
+		a, b := g.Nodes[e.From], g.Nodes[e.To]
+		e.Length = utils.HaversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+	}
+	if e.Speed > g.maxSpeed {
+		g.maxSpeed = e.Speed
+	}
+
+	idx := len(g.Edges)
+	g.Edges = append(g.Edges, e)
+	g.out[e.From] = append(g.out[e.From], idx)
+	g.grid = nil // el índice espacial debe reconstruirse
+	return idx
+}
+
+// Outgoing retorna los índices de los tramos que salen de un nodo
+func (g *Graph) Outgoing(node int) []int {
+	return g.out[node]
+}
+
+// Empty indica si el grafo no tiene tramos
+func (g *Graph) Empty() bool {
+	return len(g.Edges) == 0
+}
+
+// MaxSpeed retorna la velocidad máxima de flujo libre del grafo en km/h
+func (g *Graph) MaxSpeed() float64 {
+	return g.maxSpeed
+}
+
+// Build construye el índice espacial. Debe llamarse después de cargar el grafo
+func (g *Graph) Build() {
+	g.grid = newEdgeGrid(g)
+}
+
+// spatial retorna el índice espacial, construyéndolo si hace falta
+func (g *Graph) spatial() *edgeGrid {
+	if g.grid == nil {
+		g.Build()
+	}
+	return g.grid
+}
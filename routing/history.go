@@ -0,0 +1,269 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// historyMinSamples observaciones necesarias para usar la velocidad histórica de un tramo
+	historyMinSamples = 3
+	// classMinSamples observaciones necesarias para usar el perfil aprendido de una clase de vía
+	classMinSamples = 20
+	// LiveHorizon tiempo desde ahora durante el cual el tráfico en vivo sigue siendo representativo
+	LiveHorizon = 30 * time.Minute
+)
+
+// weekdayFactors factor típico de velocidad de flujo libre por hora en días laborables,
+// usado mientras no haya observaciones suficientes de una clase de vía
+var weekdayFactors = [24]float64{
+	1.0, 1.0, 1.0, 1.0, 1.0, 1.0, // madrugada
+	0.85,             // 6:00
+	0.45, 0.45, 0.45, // hora pico de la mañana
+	0.82, 0.82, 0.82, 0.82, 0.82, 0.82, 0.82,
+	0.45, 0.45, 0.45, // hora pico de la tarde
+	0.82, 0.82,
+	1.0, 1.0, // noche
+}
+
+// weekendFactors factor típico de velocidad de flujo libre por hora en fines de semana
+var weekendFactors = [24]float64{
+	1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.9, 0.9,
+	0.75, 0.75, 0.75, 0.75, 0.75, 0.75, 0.75, 0.75, 0.75, 0.75,
+	0.85, 0.85,
+	1.0, 1.0,
+}
+
+// localFactor clases de vía poco afectadas por las horas pico
+var localFactor = map[RoadClass]bool{
+	"residential":   true,
+	"living_street": true,
+	"service":       true,
+	"track":         true,
+}
+
+// HourlyStats valores observados por tipo de día (0 laborable, 1 fin de semana) y hora
+type HourlyStats struct {
+	Sum   [2][24]float64 `json:"sum"`
+	Count [2][24]int     `json:"count"`
+}
+
+// add registra un valor observado en un instante
+func (s *HourlyStats) add(t time.Time, value float64) {
+	day, hour := slot(t)
+	s.Sum[day][hour] += value
+	s.Count[day][hour]++
+}
+
+// mean retorna el promedio observado en un instante si reúne minSamples observaciones
+func (s *HourlyStats) mean(t time.Time, minSamples int) (float64, bool) {
+	day, hour := slot(t)
+	if s.Count[day][hour] < minSamples {
+		return 0, false
+	}
+	return s.Sum[day][hour] / float64(s.Count[day][hour]), true
+}
+
+// HistoryData contenido de un historial de velocidades para persistirlo. Los tramos se
+// identifican por su vía y sus nodos de OSM, que se conservan al recargar o actualizar
+// el mapa, y no por su posición en el grafo
+type HistoryData struct {
+	Edges   map[string]*HourlyStats    `json:"edges"`   // velocidades en km/h por tramo
+	Classes map[RoadClass]*HourlyStats `json:"classes"` // fracción del flujo libre por clase de vía
+}
+
+// SpeedHistory perfiles históricos de velocidad por hora sobre una red vial. Los tramos
+// con suficientes observaciones usan su promedio; el resto sigue el perfil de su clase
+// de vía, aprendido de las mismas observaciones o, mientras no alcancen, el típico
+type SpeedHistory struct {
+	graph   *Graph
+	edges   map[int]*HourlyStats
+	classes map[RoadClass]*HourlyStats
+	version uint64 // cantidad de observaciones registradas, para saber si cambió
+	mu      sync.RWMutex
+}
+
+// NewSpeedHistory crea un historial vacío para los tramos de un grafo
+func NewSpeedHistory(g *Graph) *SpeedHistory {
+	return &SpeedHistory{
+		graph:   g,
+		edges:   make(map[int]*HourlyStats),
+		classes: make(map[RoadClass]*HourlyStats),
+	}
+}
+
+// slot retorna el tipo de día (0 laborable, 1 fin de semana) y la hora de un instante
+func slot(t time.Time) (int, int) {
+	day := 0
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		day = 1
+	}
+	return day, t.Hour()
+}
+
+// HourlyFactor factor típico de la velocidad de flujo libre en un instante (0-1]
+func HourlyFactor(class RoadClass, t time.Time) float64 {
+	day, hour := slot(t)
+	factor := weekdayFactors[hour]
+	if day == 1 {
+		factor = weekendFactors[hour]
+	}
+	if localFactor[class] {
+		// Calles locales: la mitad del efecto de la hora pico
+		factor = 1 - (1-factor)/2
+	}
+	return factor
+}
+
+// Observe registra una velocidad medida en un tramo en un instante. También alimenta
+// el perfil de la clase de vía con la fracción de su velocidad de flujo libre
+func (h *SpeedHistory) Observe(edge int, t time.Time, speed float64) {
+	if speed <= 0 || math.IsInf(speed, 0) || math.IsNaN(speed) || edge < 0 || edge >= len(h.graph.Edges) {
+		return
+	}
+	e := &h.graph.Edges[edge]
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats, ok := h.edges[edge]
+	if !ok {
+		stats = &HourlyStats{}
+		h.edges[edge] = stats
+	}
+	stats.add(t, speed)
+	if e.Speed > 0 {
+		class, ok := h.classes[e.Class]
+		if !ok {
+			class = &HourlyStats{}
+			h.classes[e.Class] = class
+		}
+		class.add(t, math.Min(speed/e.Speed, 1))
+	}
+	h.version++
+}
+
+// Len retorna la cantidad de tramos con observaciones
+func (h *SpeedHistory) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.edges)
+}
+
+// Version retorna un contador que cambia con cada observación
+func (h *SpeedHistory) Version() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.version
+}
+
+// Factor retorna la fracción de la velocidad de flujo libre esperada en una clase de
+// vía en un instante: la aprendida si hay observaciones suficientes o la típica de
+// HourlyFactor. Sin historial (nil) se usa siempre la típica
+func (h *SpeedHistory) Factor(class RoadClass, t time.Time) float64 {
+	if h != nil {
+		h.mu.RLock()
+		stats, ok := h.classes[class]
+		if ok {
+			if factor, ok := stats.mean(t, classMinSamples); ok {
+				h.mu.RUnlock()
+				return factor
+			}
+		}
+		h.mu.RUnlock()
+	}
+	return HourlyFactor(class, t)
+}
+
+// edgeKey identificador estable de un tramo: vía y nodos de origen y destino de OSM
+func (h *SpeedHistory) edgeKey(edge int) string {
+	e := &h.graph.Edges[edge]
+	return fmt.Sprintf("%d:%d:%d", e.WayID, h.graph.Nodes[e.From].ID, h.graph.Nodes[e.To].ID)
+}
+
+// Data retorna una copia del historial para persistirlo
+func (h *SpeedHistory) Data() *HistoryData {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	data := &HistoryData{
+		Edges:   make(map[string]*HourlyStats, len(h.edges)),
+		Classes: make(map[RoadClass]*HourlyStats, len(h.classes)),
+	}
+	for edge, stats := range h.edges {
+		copied := *stats
+		data.Edges[h.edgeKey(edge)] = &copied
+	}
+	for class, stats := range h.classes {
+		copied := *stats
+		data.Classes[class] = &copied
+	}
+	return data
+}
+
+// Load reemplaza el historial por uno persistido y retorna cuántos de sus tramos
+// existen en el grafo; los demás, por ejemplo de vías que ya no están en el mapa, se
+// descartan
+func (h *SpeedHistory) Load(data *HistoryData) int {
+	keys := make(map[string]int, len(h.graph.Edges))
+	for edge := range h.graph.Edges {
+		keys[h.edgeKey(edge)] = edge
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.edges = make(map[int]*HourlyStats, len(data.Edges))
+	for key, stats := range data.Edges {
+		if edge, ok := keys[key]; ok && stats != nil {
+			copied := *stats
+			h.edges[edge] = &copied
+		}
+	}
+	h.classes = make(map[RoadClass]*HourlyStats, len(data.Classes))
+	for class, stats := range data.Classes {
+		if stats != nil {
+			copied := *stats
+			h.classes[class] = &copied
+		}
+	}
+	return len(h.edges)
+}
+
+// Speed retorna la velocidad esperada de un tramo en un instante a partir de su
+// velocidad de flujo libre. Nunca la supera para mantener la heurística admisible.
+// Sin observaciones del tramo se usa el perfil de su clase de vía (ver Factor)
+func (h *SpeedHistory) Speed(edge int, class RoadClass, speed float64, t time.Time) float64 {
+	if h != nil {
+		h.mu.RLock()
+		stats, ok := h.edges[edge]
+		if ok {
+			if observed, ok := stats.mean(t, historyMinSamples); ok {
+				h.mu.RUnlock()
+				return math.Min(speed, observed)
+			}
+		}
+		h.mu.RUnlock()
+	}
+	return speed * h.Factor(class, t)
+}
+
+// TimeWeighting retorna el peso de tiempo de viaje para una salida en depart: cada
+// tramo usa la velocidad histórica de la hora en que se lo recorre y el tráfico en
+// vivo se aplica solo mientras siga siendo representativo. history y traffic pueden ser nil
+func (r *Router) TimeWeighting(profile *Profile, traffic *Traffic, history *SpeedHistory, depart time.Time) Weighting {
+	now := time.Now()
+	return func(edge int, elapsed float64) float64 {
+		e := &r.graph.Edges[edge]
+		speed := profile.EdgeSpeed(e)
+		if profile.Motorized {
+			at := depart.Add(time.Duration(elapsed * float64(time.Second)))
+			speed = history.Speed(edge, e.Class, speed, at)
+			if at.Sub(now) < LiveHorizon && now.Sub(at) < LiveHorizon {
+				speed = traffic.Apply(edge, speed)
+			}
+		}
+		return travelTime(e.Length, speed)
+	}
+}
@@ -0,0 +1,139 @@
+package routing
+
+import (
+	"math"
+	"sort"
+)
+
+// gridCellSize tamaño de celda del índice espacial en grados (~550 m)
+const gridCellSize = 0.005
+
+// kmPerDegree distancia aproximada de un grado de latitud en km
+const kmPerDegree = 111.32
+
+type cellKey struct {
+	x, y int
+}
+
+// edgeGrid índice espacial de tramos por celdas regulares
+type edgeGrid struct {
+	graph *Graph
+	cells map[cellKey][]int
+}
+
+// Snap representa la proyección de un punto sobre un tramo de la red
+type Snap struct {
+	Edge     int     `json:"edge"`
+	Fraction float64 `json:"fraction"` // posición sobre el tramo (0 = origen, 1 = destino)
+	Lat      float64 `json:"lat"`
+	Lng      float64 `json:"lng"`
+	Distance float64 `json:"distance"` // distancia en km desde el punto original
+}
+
+func newEdgeGrid(g *Graph) *edgeGrid {
+	grid := &edgeGrid{
+		graph: g,
+		cells: make(map[cellKey][]int),
+	}
+
+	for i, e := range g.Edges {
+		a, b := g.Nodes[e.From], g.Nodes[e.To]
+		minX, maxX := cellCoord(math.Min(a.Lng, b.Lng)), cellCoord(math.Max(a.Lng, b.Lng))
+		minY, maxY := cellCoord(math.Min(a.Lat, b.Lat)), cellCoord(math.Max(a.Lat, b.Lat))
+		for x := minX; x <= maxX; x++ {
+			for y := minY; y <= maxY; y++ {
+				key := cellKey{x, y}
+				grid.cells[key] = append(grid.cells[key], i)
+			}
+		}
+	}
+
+	return grid
+}
+
+func cellCoord(deg float64) int {
+	return int(math.Floor(deg / gridCellSize))
+}
+
+// nearby retorna las proyecciones sobre tramos a menos de radius km, ordenadas por distancia
+func (grid *edgeGrid) nearby(lat, lng, radius float64) []Snap {
+	latSpan := radius / kmPerDegree
+	lngSpan := radius / (kmPerDegree * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+
+	minX, maxX := cellCoord(lng-lngSpan), cellCoord(lng+lngSpan)
+	minY, maxY := cellCoord(lat-latSpan), cellCoord(lat+latSpan)
+
+	seen := make(map[int]bool)
+	snaps := make([]Snap, 0)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for _, idx := range grid.cells[cellKey{x, y}] {
+				if seen[idx] {
+					continue
+				}
+				seen[idx] = true
+
+				snap := grid.graph.project(idx, lat, lng)
+				if snap.Distance <= radius {
+					snaps = append(snaps, snap)
+				}
+			}
+		}
+	}
+
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].Distance < snaps[j].Distance
+	})
+	return snaps
+}
+
+// project proyecta un punto sobre un tramo usando una aproximación equirectangular local
+func (g *Graph) project(edge int, lat, lng float64) Snap {
+	e := g.Edges[edge]
+	a, b := g.Nodes[e.From], g.Nodes[e.To]
+
+	cosLat := math.Cos(lat * math.Pi / 180)
+	ax, ay := (a.Lng-lng)*cosLat, a.Lat-lat
+	bx, by := (b.Lng-lng)*cosLat, b.Lat-lat
+	dx, dy := bx-ax, by-ay
+
+	fraction := 0.0
+	if lenSq := dx*dx + dy*dy; lenSq > 0 {
+		fraction = -(ax*dx + ay*dy) / lenSq
+		fraction = math.Max(0, math.Min(1, fraction))
+	}
+
+	px, py := ax+fraction*dx, ay+fraction*dy
+	return Snap{
+		Edge:     edge,
+		Fraction: fraction,
+		Lat:      a.Lat + fraction*(b.Lat-a.Lat),
+		Lng:      a.Lng + fraction*(b.Lng-a.Lng),
+		Distance: math.Sqrt(px*px+py*py) * kmPerDegree,
+	}
+}
+
+// Nearby retorna los tramos a menos de radius km de un punto, ordenados por distancia
+func (g *Graph) Nearby(lat, lng, radius float64) []Snap {
+	return g.spatial().nearby(lat, lng, radius)
+}
+
+// Snap ubica un punto sobre el tramo permitido más cercano (y su sentido contrario,
+// si existe) dentro de maxDist km. allowed puede ser nil. Retorna nil si no hay tramos cercanos
+func (g *Graph) Snap(lat, lng, maxDist float64, allowed func(edge int) bool) []Snap {
+	var snaps []Snap
+	for _, c := range g.Nearby(lat, lng, maxDist) {
+		if allowed != nil && !allowed(c.Edge) {
+			continue
+		}
+		if len(snaps) == 0 {
+			snaps = append(snaps, c)
+			continue
+		}
+		be, ce := g.Edges[snaps[0].Edge], g.Edges[c.Edge]
+		if ce.From == be.To && ce.To == be.From {
+			snaps = append(snaps, c)
+		}
+	}
+	return snaps
+}
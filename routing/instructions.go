@@ -0,0 +1,200 @@
+package routing
+
+import (
+	"gowaze/models"
+	"gowaze/utils"
+	"math"
+)
+
+// Tipos de maniobra de las instrucciones paso a paso
+const (
+	ManeuverDepart     = "depart"
+	ManeuverTurn       = "turn"
+	ManeuverContinue   = "continue"
+	ManeuverKeep       = "keep"
+	ManeuverRoundabout = "roundabout"
+	ManeuverWaypoint   = "waypoint"
+	ManeuverArrive     = "arrive"
+)
+
+// Umbrales de ángulo de giro en grados
+const (
+	straightAngle = 15
+	slightAngle   = 45
+	sharpAngle    = 135
+	uturnAngle    = 170
+	// forkAngle ángulo máximo entre opciones para considerar una bifurcación
+	forkAngle = 50
+)
+
+// Instructions genera las instrucciones paso a paso de un camino en el idioma dado ("es" o "en")
+func (r *Router) Instructions(path *Path, lang string) []models.RouteStep {
+	g := r.graph
+	segments := path.Segments
+	if len(segments) == 0 {
+		return nil
+	}
+
+	first := &g.Edges[segments[0].Edge]
+	steps := []models.RouteStep{r.newStep(ManeuverDepart, "", first, path.Points[0])}
+	current := 0
+	roundaboutExits := 0
+
+	for k, seg := range segments {
+		e := &g.Edges[seg.Edge]
+
+		if k > 0 {
+			prev := &g.Edges[segments[k-1].Edge]
+			location := path.Points[k]
+
+			switch {
+			case e.Roundabout && !prev.Roundabout:
+				// Exit queda en 0 si el camino termina dentro de la rotonda
+				steps = append(steps, r.newStep(ManeuverRoundabout, "", e, location))
+				current = len(steps) - 1
+				roundaboutExits = 0
+			case e.Roundabout && prev.Roundabout:
+				roundaboutExits += r.countExits(e.From)
+			case prev.Roundabout:
+				// Salida de la rotonda: se completa la maniobra iniciada al entrar
+				steps[current].Exit = roundaboutExits + 1
+				steps[current].Street = e.Name
+				steps[current].Bearing = math.Round(r.edgeBearing(e))
+				steps[current].Direction = utils.GetCardinalDirection(steps[current].Bearing)
+			default:
+				if maneuver, modifier := r.classifyTurn(prev, e); maneuver != "" {
+					steps = append(steps, r.newStep(maneuver, modifier, e, location))
+					current = len(steps) - 1
+				}
+			}
+		}
+
+		steps[current].Distance += seg.Distance
+		steps[current].Duration += int(math.Round(seg.Duration))
+	}
+
+	last := &g.Edges[segments[len(segments)-1].Edge]
+	steps = append(steps, r.newStep(ManeuverArrive, "", last, path.Points[len(path.Points)-1]))
+
+	for i := range steps {
+		steps[i].Instruction = Localize(steps[i], lang)
+	}
+	return steps
+}
+
+// newStep crea una maniobra sobre el tramo e
+func (r *Router) newStep(maneuver, modifier string, e *Edge, location models.Location) models.RouteStep {
+	bearing := r.edgeBearing(e)
+	return models.RouteStep{
+		Maneuver:  maneuver,
+		Modifier:  modifier,
+		Street:    e.Name,
+		Bearing:   math.Round(bearing),
+		Direction: utils.GetCardinalDirection(bearing),
+		Location:  location,
+	}
+}
+
+// edgeBearing rumbo de un tramo desde su nodo origen a su nodo destino
+func (r *Router) edgeBearing(e *Edge) float64 {
+	a, b := r.graph.Nodes[e.From], r.graph.Nodes[e.To]
+	return utils.CalculateBearing(a.Lat, a.Lng, b.Lat, b.Lng)
+}
+
+// turnAngle ángulo de giro entre dos tramos en (-180, 180]. Negativo es a la izquierda
+func (r *Router) turnAngle(in, out *Edge) float64 {
+	angle := r.edgeBearing(out) - r.edgeBearing(in)
+	for angle > 180 {
+		angle -= 360
+	}
+	for angle <= -180 {
+		angle += 360
+	}
+	return angle
+}
+
+// classifyTurn decide si el paso de in a out requiere una maniobra y de qué tipo
+func (r *Router) classifyTurn(in, out *Edge) (string, string) {
+	angle := r.turnAngle(in, out)
+	modifier := turnModifier(angle)
+
+	// Opciones en la intersección, excluyendo regresar por donde se vino
+	var others []float64
+	for _, idx := range r.graph.Outgoing(in.To) {
+		option := &r.graph.Edges[idx]
+		if option == out || option.To == in.From {
+			continue
+		}
+		others = append(others, r.turnAngle(in, option))
+	}
+
+	// Bifurcación: otra opción casi en la misma dirección
+	if math.Abs(angle) < forkAngle {
+		left, right := true, true
+		fork := false
+		for _, other := range others {
+			if math.Abs(other) < forkAngle {
+				fork = true
+				if other < angle {
+					left = false
+				} else {
+					right = false
+				}
+			}
+		}
+		if fork {
+			switch {
+			case left:
+				return ManeuverKeep, "left"
+			case right:
+				return ManeuverKeep, "right"
+			}
+			return ManeuverContinue, "straight"
+		}
+	}
+
+	if out.Name != in.Name {
+		if modifier == "straight" {
+			return ManeuverContinue, modifier
+		}
+		return ManeuverTurn, modifier
+	}
+
+	// Misma vía: solo indicar giros en intersecciones reales
+	if modifier != "straight" && len(others) > 0 {
+		return ManeuverTurn, modifier
+	}
+	return "", ""
+}
+
+// turnModifier clasifica un ángulo de giro
+func turnModifier(angle float64) string {
+	abs := math.Abs(angle)
+	side := "right"
+	if angle < 0 {
+		side = "left"
+	}
+
+	switch {
+	case abs < straightAngle:
+		return "straight"
+	case abs < slightAngle:
+		return "slight_" + side
+	case abs < sharpAngle:
+		return side
+	case abs < uturnAngle:
+		return "sharp_" + side
+	}
+	return "uturn"
+}
+
+// countExits cuenta las salidas de una rotonda en un nodo
+func (r *Router) countExits(node int) int {
+	exits := 0
+	for _, idx := range r.graph.Outgoing(node) {
+		if !r.graph.Edges[idx].Roundabout {
+			exits++
+		}
+	}
+	return exits
+}
@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"container/heap"
+	"gowaze/models"
+	"gowaze/utils"
+	"math"
+)
+
+// isochroneSectors cantidad de sectores angulares usados para trazar los contornos
+const isochroneSectors = 72
+
+// Isochrone calcula, para cada límite de tiempo en segundos, el contorno de la zona
+// alcanzable desde un punto. Los contornos se retornan en el mismo orden que limits;
+// un contorno es nil si la zona alcanzable es demasiado pequeña para formar un polígono
+func (r *Router) Isochrone(from models.Location, weighting Weighting, limits []float64) ([][]models.Location, error) {
+	if r.graph.Empty() {
+		return nil, ErrNoGraph
+	}
+	start := r.graph.Snap(from.Lat, from.Lng, r.MaxSnapDistance, passable(weighting))
+	if start == nil {
+		return nil, ErrNoSnap
+	}
+
+	maxLimit := 0.0
+	for _, limit := range limits {
+		maxLimit = math.Max(maxLimit, limit)
+	}
+
+	dist := r.travelTimes(start, weighting, maxLimit)
+	origin := models.Location{Lat: start[0].Lat, Lng: start[0].Lng}
+	contours := make([][]models.Location, len(limits))
+	for i, limit := range limits {
+		contours[i] = starContour(origin, r.reachablePoints(start, dist, weighting, limit))
+	}
+	return contours, nil
+}
+
+// travelTimes ejecuta Dijkstra desde las proyecciones de origen hasta agotar maxTime
+// segundos. Los nodos no alcanzados quedan con tiempo infinito
+func (r *Router) travelTimes(start []Snap, weighting Weighting, maxTime float64) []float64 {
+	g := r.graph
+	dist := make([]float64, len(g.Nodes))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+
+	pq := &nodeQueue{}
+	push := func(node int, d float64) {
+		dist[node] = d
+		heap.Push(pq, nodeItem{node: node, dist: d, priority: d})
+	}
+
+	for _, s := range start {
+		if d := (1 - s.Fraction) * weighting(s.Edge, 0); d <= maxTime && d < dist[g.Edges[s.Edge].To] {
+			push(g.Edges[s.Edge].To, d)
+		}
+	}
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(nodeItem)
+		u := item.node
+		if item.dist > dist[u] {
+			continue
+		}
+
+		for _, edgeIdx := range g.out[u] {
+			v := g.Edges[edgeIdx].To
+			if d := dist[u] + weighting(edgeIdx, dist[u]); d <= maxTime && d < dist[v] {
+				push(v, d)
+			}
+		}
+	}
+
+	return dist
+}
+
+// reachablePoints nodos alcanzables dentro de limit segundos junto con los puntos
+// intermedios de los tramos que se alcanzan a recorrer solo en parte
+func (r *Router) reachablePoints(start []Snap, dist []float64, weighting Weighting, limit float64) []models.Location {
+	g := r.graph
+	var points []models.Location
+
+	for _, s := range start {
+		if w := weighting(s.Edge, 0); (1-s.Fraction)*w > limit {
+			points = append(points, g.pointAt(s.Edge, s.Fraction+limit/w))
+		}
+	}
+
+	for u, d := range dist {
+		if d > limit {
+			continue
+		}
+		points = append(points, models.Location{Lat: g.Nodes[u].Lat, Lng: g.Nodes[u].Lng})
+
+		for _, edgeIdx := range g.out[u] {
+			w := weighting(edgeIdx, d)
+			if math.IsInf(w, 1) || d+w <= limit {
+				continue
+			}
+			points = append(points, g.pointAt(edgeIdx, (limit-d)/w))
+		}
+	}
+
+	return points
+}
+
+// pointAt punto ubicado en una fracción de un tramo
+func (g *Graph) pointAt(edge int, fraction float64) models.Location {
+	e := g.Edges[edge]
+	a, b := g.Nodes[e.From], g.Nodes[e.To]
+	return models.Location{
+		Lat: a.Lat + fraction*(b.Lat-a.Lat),
+		Lng: a.Lng + fraction*(b.Lng-a.Lng),
+	}
+}
+
+// starContour traza un polígono con el punto más lejano del origen en cada sector angular
+func starContour(origin models.Location, points []models.Location) []models.Location {
+	var farthest [isochroneSectors]*models.Location
+	var distances [isochroneSectors]float64
+
+	for i := range points {
+		p := &points[i]
+		d := utils.HaversineDistance(origin.Lat, origin.Lng, p.Lat, p.Lng)
+		if d < 0.001 {
+			continue
+		}
+		bearing := utils.CalculateBearing(origin.Lat, origin.Lng, p.Lat, p.Lng)
+		sector := int(bearing/(360.0/isochroneSectors)) % isochroneSectors
+		if d > distances[sector] {
+			farthest[sector] = p
+			distances[sector] = d
+		}
+	}
+
+	var contour []models.Location
+	for _, p := range farthest {
+		if p != nil {
+			contour = append(contour, *p)
+		}
+	}
+	if len(contour) < 3 {
+		return nil
+	}
+	return contour
+}
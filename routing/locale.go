@@ -0,0 +1,138 @@
+package routing
+
+import (
+	"fmt"
+	"gowaze/models"
+	"strings"
+)
+
+// DefaultLanguage idioma por defecto de las instrucciones
+const DefaultLanguage = "es"
+
+// instructionTexts textos de las instrucciones por idioma
+var instructionTexts = map[string]map[string]string{
+	"es": {
+		ManeuverDepart:     "Dirígete al %s",
+		ManeuverTurn:       "Gira %s",
+		ManeuverContinue:   "Continúa %s",
+		ManeuverKeep:       "Mantente %s",
+		ManeuverRoundabout: "En la rotonda, toma la %s salida",
+		"roundabout_enter": "Entra en la rotonda",
+		ManeuverWaypoint:   "Has llegado a tu parada",
+		ManeuverArrive:     "Has llegado a tu destino",
+		"uturn":            "Da la vuelta en U",
+		"onto":             " por %s",
+		"toward":           " hacia %s",
+	},
+	"en": {
+		ManeuverDepart:     "Head %s",
+		ManeuverTurn:       "Turn %s",
+		ManeuverContinue:   "Continue %s",
+		ManeuverKeep:       "Keep %s",
+		ManeuverRoundabout: "At the roundabout, take the %s exit",
+		"roundabout_enter": "Enter the roundabout",
+		ManeuverWaypoint:   "You have reached your stop",
+		ManeuverArrive:     "You have arrived at your destination",
+		"uturn":            "Make a U-turn",
+		"onto":             " on %s",
+		"toward":           " onto %s",
+	},
+}
+
+// modifierTexts dirección de giro por idioma
+var modifierTexts = map[string]map[string]string{
+	"es": {
+		"left":         "a la izquierda",
+		"right":        "a la derecha",
+		"slight_left":  "ligeramente a la izquierda",
+		"slight_right": "ligeramente a la derecha",
+		"sharp_left":   "bruscamente a la izquierda",
+		"sharp_right":  "bruscamente a la derecha",
+		"straight":     "recto",
+	},
+	"en": {
+		"left":         "left",
+		"right":        "right",
+		"slight_left":  "slightly left",
+		"slight_right": "slightly right",
+		"sharp_left":   "sharp left",
+		"sharp_right":  "sharp right",
+		"straight":     "straight",
+	},
+}
+
+// cardinalTexts puntos cardinales por idioma
+var cardinalTexts = map[string]map[string]string{
+	"es": {"N": "norte", "NE": "noreste", "E": "este", "SE": "sureste", "S": "sur", "SW": "suroeste", "W": "oeste", "NW": "noroeste"},
+	"en": {"N": "north", "NE": "northeast", "E": "east", "SE": "southeast", "S": "south", "SW": "southwest", "W": "west", "NW": "northwest"},
+}
+
+// NormalizeLanguage reduce un código de idioma ("en-US", "ES") a uno soportado
+func NormalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := instructionTexts[lang]; ok {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// Localize genera el texto de una maniobra en el idioma dado
+func Localize(step models.RouteStep, lang string) string {
+	lang = NormalizeLanguage(lang)
+	texts := instructionTexts[lang]
+
+	var text string
+	street := texts["toward"]
+	switch step.Maneuver {
+	case ManeuverDepart:
+		text = fmt.Sprintf(texts[ManeuverDepart], cardinalTexts[lang][step.Direction])
+		street = texts["onto"]
+	case ManeuverRoundabout:
+		// Sin salida: el destino está dentro de la rotonda
+		if step.Exit == 0 {
+			text = texts["roundabout_enter"]
+			street = texts["onto"]
+			break
+		}
+		text = fmt.Sprintf(texts[ManeuverRoundabout], ordinal(step.Exit, lang))
+	case ManeuverWaypoint, ManeuverArrive:
+		return texts[step.Maneuver]
+	default:
+		if step.Modifier == "uturn" {
+			text = texts["uturn"]
+			street = texts["onto"]
+		} else {
+			text = fmt.Sprintf(texts[step.Maneuver], modifierTexts[lang][step.Modifier])
+		}
+		if step.Maneuver == ManeuverContinue {
+			street = texts["onto"]
+		}
+	}
+
+	if step.Street != "" {
+		text += fmt.Sprintf(street, step.Street)
+	}
+	return text
+}
+
+// ordinal formatea el número de salida de una rotonda
+func ordinal(n int, lang string) string {
+	if lang == "es" {
+		return fmt.Sprintf("%dª", n)
+	}
+
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,282 @@
+package routing
+
+import (
+	"errors"
+	"gowaze/models"
+	"gowaze/utils"
+	"math"
+	"time"
+)
+
+const (
+	// matchRadius radio en km de búsqueda de tramos candidatos para cada punto
+	matchRadius = 0.05
+	// matchCandidates cantidad máxima de candidatos por punto
+	matchCandidates = 6
+	// gpsSigma desviación estándar del error de posición GPS en km
+	gpsSigma = 0.015
+	// transitionBeta escala en km de la diferencia entre la distancia por la red y en línea recta
+	transitionBeta = 0.05
+	// maxDetour distancia adicional en km que se permite recorrer por la red entre dos puntos
+	maxDetour = 2.0
+)
+
+// ErrNoMatch indica que ningún punto de la traza pudo ajustarse a la red vial
+var ErrNoMatch = errors.New("la traza no se pudo ajustar a la red vial")
+
+// matchState candidato de un punto con el mejor puntaje acumulado que llega a él
+type matchState struct {
+	snap  Snap
+	score float64 // log-probabilidad acumulada
+	back  int     // estado elegido en la capa anterior
+}
+
+// matchLayer candidatos de un punto de la traza
+type matchLayer struct {
+	point  int
+	states []matchState
+}
+
+// Match ajusta una secuencia de posiciones GPS a la red vial con un modelo oculto de
+// Markov: la probabilidad de emisión decae con la distancia del punto al tramo y la de
+// transición con la diferencia entre la distancia por la red y en línea recta. Viterbi
+// elige la secuencia de tramos más probable. Si dos puntos consecutivos no pueden
+// unirse por la red la traza se corta y el ajuste continúa desde el punto siguiente
+func (r *Router) Match(trace []models.TracePoint, profile *Profile) (*models.MatchedTrace, error) {
+	g := r.graph
+	if g.Empty() {
+		return nil, ErrNoGraph
+	}
+
+	// Costo por distancia expresado en segundos a la velocidad máxima de la red
+	// para mantener admisible la heurística de A*
+	maxSpeed := math.Max(g.MaxSpeed(), 1)
+	cost := func(edge int, elapsed float64) float64 {
+		e := &g.Edges[edge]
+		if !profile.Allows(e) {
+			return math.Inf(1)
+		}
+		return travelTime(e.Length, maxSpeed)
+	}
+	allowed := passable(cost)
+
+	var chains [][]matchLayer
+	var chain []matchLayer
+	for i, p := range trace {
+		candidates := r.matchCandidates(p, allowed)
+		if len(candidates) == 0 {
+			continue
+		}
+
+		layer := matchLayer{point: i, states: make([]matchState, len(candidates))}
+		for j, c := range candidates {
+			layer.states[j] = matchState{snap: c, score: emissionScore(c), back: -1}
+		}
+
+		if len(chain) > 0 && !r.linkLayer(chain[len(chain)-1], &layer, trace, cost, maxSpeed) {
+			// Sin transición posible: cortar la traza
+			chains = append(chains, chain)
+			chain = nil
+		}
+		chain = append(chain, layer)
+	}
+	if len(chain) > 0 {
+		chains = append(chains, chain)
+	}
+	if len(chains) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	result := &models.MatchedTrace{
+		Points:   make([]*models.Location, len(trace)),
+		Segments: []models.MatchedSegment{},
+	}
+	for _, chain := range chains {
+		r.appendChain(result, chain, trace, cost)
+	}
+	return result, nil
+}
+
+// matchCandidates proyecciones sobre los tramos permitidos más cercanos a un punto
+func (r *Router) matchCandidates(p models.TracePoint, allowed func(edge int) bool) []Snap {
+	var candidates []Snap
+	for _, snap := range r.graph.Nearby(p.Lat, p.Lng, matchRadius) {
+		if !allowed(snap.Edge) {
+			continue
+		}
+		candidates = append(candidates, snap)
+		if len(candidates) == matchCandidates {
+			break
+		}
+	}
+	return candidates
+}
+
+// emissionScore log-probabilidad de observar el punto dado el candidato
+func emissionScore(c Snap) float64 {
+	return -0.5 * (c.Distance / gpsSigma) * (c.Distance / gpsSigma)
+}
+
+// linkLayer calcula el mejor estado anterior para cada candidato de layer.
+// Retorna false si ningún candidato es alcanzable desde la capa anterior
+func (r *Router) linkLayer(prev matchLayer, layer *matchLayer, trace []models.TracePoint, cost Weighting, maxSpeed float64) bool {
+	a, b := trace[prev.point], trace[layer.point]
+	straight := utils.HaversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+
+	ends := make([][]Snap, len(layer.states))
+	for j := range layer.states {
+		ends[j] = []Snap{layer.states[j].snap}
+	}
+
+	best := make([]float64, len(layer.states))
+	for j := range best {
+		best[j] = math.Inf(-1)
+	}
+
+	for k, ps := range prev.states {
+		cells := r.oneToMany([]Snap{ps.snap}, ends, cost, travelTime(straight+maxDetour, maxSpeed))
+		for j := range layer.states {
+			c := layer.states[j].snap
+			routeDist, ok := cells[j].Distance, cells[j].Reachable
+			if backtrack(ps.snap, c, r.graph) {
+				// Retroceso dentro del mismo tramo por ruido GPS: sin movimiento
+				routeDist, ok = 0, true
+			}
+			if !ok {
+				continue
+			}
+
+			score := ps.score + layer.states[j].score - math.Abs(routeDist-straight)/transitionBeta
+			if score > best[j] {
+				best[j] = score
+				layer.states[j].back = k
+			}
+		}
+	}
+
+	linked := false
+	for j := range layer.states {
+		layer.states[j].score = best[j]
+		if !math.IsInf(best[j], -1) {
+			linked = true
+		}
+	}
+	if !linked {
+		// La capa inicia una nueva cadena con su puntaje de emisión
+		for j := range layer.states {
+			layer.states[j].score = emissionScore(layer.states[j].snap)
+			layer.states[j].back = -1
+		}
+	}
+	return linked
+}
+
+// backtrack indica si b queda levemente detrás de a sobre el mismo tramo
+func backtrack(a, b Snap, g *Graph) bool {
+	return a.Edge == b.Edge && b.Fraction < a.Fraction &&
+		(a.Fraction-b.Fraction)*g.Edges[a.Edge].Length <= 2*gpsSigma
+}
+
+// appendChain reconstruye la secuencia más probable de una cadena y agrega al
+// resultado los caminos entre puntos consecutivos con sus tiempos
+func (r *Router) appendChain(result *models.MatchedTrace, chain []matchLayer, trace []models.TracePoint, cost Weighting) {
+	g := r.graph
+
+	// Viterbi: retroceder desde el mejor estado final
+	chosen := make([]Snap, len(chain))
+	state := 0
+	last := chain[len(chain)-1].states
+	for j := range last {
+		if last[j].score > last[state].score {
+			state = j
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		chosen[i] = chain[i].states[state].snap
+		state = chain[i].states[state].back
+	}
+
+	for i, snap := range chosen {
+		if i > 0 && backtrack(chosen[i-1], snap, g) {
+			chosen[i] = chosen[i-1]
+		}
+		result.Points[chain[i].point] = &models.Location{Lat: chosen[i].Lat, Lng: chosen[i].Lng}
+	}
+
+	result.Path = append(result.Path, models.Location{Lat: chosen[0].Lat, Lng: chosen[0].Lng})
+
+	for i := 1; i < len(chosen); i++ {
+		a, b := chosen[i-1], chosen[i]
+		if a == b {
+			continue
+		}
+		path, err := r.shortestPath([]Snap{a}, []Snap{b}, cost, nil)
+		if err != nil || path.Distance <= 0 {
+			continue
+		}
+
+		startTime := trace[chain[i-1].point].Timestamp
+		elapsed := trace[chain[i].point].Timestamp.Sub(startTime).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+
+		offset := 0.0
+		for k, seg := range path.Segments {
+			duration := elapsed * seg.Distance / path.Distance
+			segStart := startTime.Add(secondsToDuration(offset))
+			offset += duration
+			from, to := path.Points[k], path.Points[k+1]
+
+			n := len(result.Segments)
+			if n > 0 && result.Segments[n-1].Edge == seg.Edge && absDuration(segStart.Sub(result.Segments[n-1].EndTime)) < time.Millisecond {
+				// Mismo tramo recorrido entre varios puntos de la traza
+				merged := &result.Segments[n-1]
+				merged.To = to
+				merged.Distance += seg.Distance
+				merged.Duration += duration
+				merged.EndTime = startTime.Add(secondsToDuration(offset))
+				merged.Speed = observedSpeed(merged.Distance, merged.Duration)
+			} else {
+				e := &g.Edges[seg.Edge]
+				result.Segments = append(result.Segments, models.MatchedSegment{
+					Edge:      seg.Edge,
+					WayID:     e.WayID,
+					Street:    e.Name,
+					From:      from,
+					To:        to,
+					Distance:  seg.Distance,
+					Duration:  duration,
+					Speed:     observedSpeed(seg.Distance, duration),
+					StartTime: segStart,
+					EndTime:   startTime.Add(secondsToDuration(offset)),
+				})
+			}
+		}
+
+		result.Path = append(result.Path, path.Points[1:]...)
+		result.Distance += path.Distance
+		result.Duration += elapsed
+	}
+}
+
+// observedSpeed velocidad en km/h a partir de distancia en km y duración en segundos
+func observedSpeed(distance, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return distance / seconds * 3600
+}
+
+// secondsToDuration convierte segundos a time.Duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// absDuration valor absoluto de una duración
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
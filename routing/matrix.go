@@ -0,0 +1,160 @@
+package routing
+
+import (
+	"container/heap"
+	"gowaze/models"
+	"math"
+)
+
+// labels distancias de una búsqueda de Dijkstra por nodo. Se reutilizan entre
+// búsquedas y solo se reinician los nodos alcanzados, así una búsqueda acotada, como
+// las del ajuste de trazas, no asigna ni recorre arreglos del tamaño del grafo
+type labels struct {
+	dist    []float64 // costo desde el origen, infinito si no se alcanzó
+	length  []float64 // km desde el origen
+	touched []int     // nodos con costo finito
+}
+
+// set asigna el costo y la distancia de un nodo
+func (l *labels) set(node int, d, length float64) {
+	if math.IsInf(l.dist[node], 1) {
+		l.touched = append(l.touched, node)
+	}
+	l.dist[node] = d
+	l.length[node] = length
+}
+
+// searchLabels obtiene etiquetas sin nodos alcanzados para una búsqueda
+func (r *Router) searchLabels() *labels {
+	n := len(r.graph.Nodes)
+	if l, ok := r.labelPool.Get().(*labels); ok && len(l.dist) == n {
+		return l
+	}
+	l := &labels{dist: make([]float64, n), length: make([]float64, n)}
+	for i := range l.dist {
+		l.dist[i] = math.Inf(1)
+	}
+	return l
+}
+
+// releaseLabels reinicia los nodos alcanzados y devuelve las etiquetas para reutilizarlas
+func (r *Router) releaseLabels(l *labels) {
+	for _, node := range l.touched {
+		l.dist[node] = math.Inf(1)
+		l.length[node] = 0
+	}
+	l.touched = l.touched[:0]
+	r.labelPool.Put(l)
+}
+
+// MatrixCell tiempo y distancia de viaje entre un origen y un destino
+type MatrixCell struct {
+	Duration  float64 `json:"duration"` // en segundos
+	Distance  float64 `json:"distance"` // en km
+	Reachable bool    `json:"reachable"`
+}
+
+// OneToMany calcula con una sola búsqueda de Dijkstra el tiempo y la distancia desde
+// un origen hasta cada destino. Los destinos fuera de la red quedan como no alcanzables
+func (r *Router) OneToMany(from models.Location, to []models.Location, weighting Weighting) ([]MatrixCell, error) {
+	g := r.graph
+	if g.Empty() {
+		return nil, ErrNoGraph
+	}
+	allowed := passable(weighting)
+	start := g.Snap(from.Lat, from.Lng, r.MaxSnapDistance, allowed)
+	if start == nil {
+		return nil, ErrNoSnap
+	}
+
+	ends := make([][]Snap, len(to))
+	for i, loc := range to {
+		ends[i] = g.Snap(loc.Lat, loc.Lng, r.MaxSnapDistance, allowed)
+	}
+	return r.oneToMany(start, ends, weighting, math.Inf(1)), nil
+}
+
+// oneToMany ejecuta Dijkstra desde las proyecciones de origen hasta resolver todas las
+// proyecciones de destino o superar maxCost
+func (r *Router) oneToMany(start []Snap, ends [][]Snap, weighting Weighting, maxCost float64) []MatrixCell {
+	g := r.graph
+	cells := make([]MatrixCell, len(ends))
+	for i := range cells {
+		cells[i].Duration = math.Inf(1)
+	}
+
+	// Nodos que deben quedar resueltos antes de poder evaluar cada destino
+	pending := make(map[int]bool)
+	for i := range ends {
+		for _, t := range ends[i] {
+			pending[g.Edges[t.Edge].From] = true
+		}
+	}
+
+	labels := r.searchLabels()
+	defer r.releaseLabels(labels)
+	dist, length := labels.dist, labels.length
+
+	pq := &nodeQueue{}
+	push := func(node int, d, l float64) {
+		labels.set(node, d, l)
+		heap.Push(pq, nodeItem{node: node, dist: d, priority: d})
+	}
+
+	for _, s := range start {
+		e := &g.Edges[s.Edge]
+		if d := (1 - s.Fraction) * weighting(s.Edge, 0); d <= maxCost && d < dist[e.To] {
+			push(e.To, d, (1-s.Fraction)*e.Length)
+		}
+
+		// Origen y destino sobre el mismo tramo
+		for i := range ends {
+			for _, t := range ends[i] {
+				if t.Edge != s.Edge || t.Fraction < s.Fraction {
+					continue
+				}
+				if d := (t.Fraction - s.Fraction) * weighting(s.Edge, 0); d < cells[i].Duration {
+					cells[i] = MatrixCell{Duration: d, Distance: (t.Fraction - s.Fraction) * e.Length, Reachable: true}
+				}
+			}
+		}
+	}
+
+	for pq.Len() > 0 && len(pending) > 0 {
+		item := heap.Pop(pq).(nodeItem)
+		u := item.node
+		if item.dist > dist[u] {
+			continue
+		}
+		delete(pending, u)
+
+		for _, edgeIdx := range g.out[u] {
+			w := weighting(edgeIdx, dist[u])
+			if math.IsInf(w, 1) {
+				continue
+			}
+			e := &g.Edges[edgeIdx]
+			if d := dist[u] + w; d <= maxCost && d < dist[e.To] {
+				push(e.To, d, length[u]+e.Length)
+			}
+		}
+	}
+
+	for i := range ends {
+		for _, t := range ends[i] {
+			e := &g.Edges[t.Edge]
+			u := e.From
+			if math.IsInf(dist[u], 1) {
+				continue
+			}
+			if d := dist[u] + t.Fraction*weighting(t.Edge, dist[u]); d < cells[i].Duration {
+				cells[i] = MatrixCell{Duration: d, Distance: length[u] + t.Fraction*e.Length, Reachable: true}
+			}
+		}
+		if !cells[i].Reachable {
+			cells[i].Duration = 0
+		}
+	}
+
+	return cells
+}
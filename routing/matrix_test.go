@@ -0,0 +1,43 @@
+package routing
+
+import (
+	"gowaze/models"
+	"math"
+	"testing"
+)
+
+func TestOneToManyReusesLabels(t *testing.T) {
+	g := ladderGraph()
+	router := NewRouter(g)
+	from := models.Location{Lat: 0, Lng: 0.001}
+	to := []models.Location{
+		{Lat: 0, Lng: 0.019},
+		{Lat: -0.002, Lng: 0.019},
+		{Lat: 0, Lng: 0.028}, // tramo aislado
+	}
+
+	want := []MatrixCell{
+		{Duration: seconds(0.018, 80), Distance: 0.018 * kmPerDeg, Reachable: true},
+		{Duration: seconds(0.009, 80) + seconds(0.002, 50) + seconds(0.009, 75), Distance: 0.02 * kmPerDeg, Reachable: true},
+		{},
+	}
+
+	// Una búsqueda acotada en medio no debe dejar etiquetas que afecten a las siguientes
+	for i := 0; i < 3; i++ {
+		if i == 1 {
+			start := g.Snap(from.Lat, from.Lng, router.MaxSnapDistance, nil)
+			end := g.Snap(to[2].Lat, to[2].Lng, router.MaxSnapDistance, nil)
+			router.oneToMany(start, [][]Snap{end}, router.FreeFlow(), 60)
+		}
+		cells, err := router.OneToMany(from, to, router.FreeFlow())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, cell := range cells {
+			if cell.Reachable != want[j].Reachable ||
+				math.Abs(cell.Duration-want[j].Duration) > 0.1 || math.Abs(cell.Distance-want[j].Distance) > 1e-3 {
+				t.Errorf("búsqueda %d, destino %d = %+v, esperado %+v", i, j, cell, want[j])
+			}
+		}
+	}
+}
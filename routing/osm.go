@@ -0,0 +1,334 @@
+package routing
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RoadClass clasificación de una vía según la etiqueta highway de OSM
+type RoadClass string
+
+// defaultSpeeds velocidades por defecto (km/h) para cada clase de vía transitable
+var defaultSpeeds = map[RoadClass]float64{
+	"motorway":       100,
+	"motorway_link":  60,
+	"trunk":          80,
+	"trunk_link":     50,
+	"primary":        60,
+	"primary_link":   40,
+	"secondary":      50,
+	"secondary_link": 35,
+	"tertiary":       40,
+	"tertiary_link":  30,
+	"unclassified":   30,
+	"residential":    30,
+	"living_street":  10,
+	"service":        20,
+	"road":           30,
+}
+
+// classAccess modos de transporte permitidos por defecto en cada clase de vía
+var classAccess = map[RoadClass]AccessMask{
+	"motorway":       accessMotor,
+	"motorway_link":  accessMotor,
+	"trunk":          accessAll,
+	"trunk_link":     accessAll,
+	"primary":        accessAll,
+	"primary_link":   accessAll,
+	"secondary":      accessAll,
+	"secondary_link": accessAll,
+	"tertiary":       accessAll,
+	"tertiary_link":  accessAll,
+	"unclassified":   accessAll,
+	"residential":    accessAll,
+	"living_street":  accessAll,
+	"service":        accessAll,
+	"road":           accessAll,
+	"track":          AccessCar | AccessMotorcycle | AccessBicycle | AccessFoot,
+	"cycleway":       AccessBicycle,
+	"path":           AccessBicycle | AccessFoot,
+	"footway":        AccessFoot,
+	"pedestrian":     AccessFoot,
+	"steps":          AccessFoot,
+}
+
+// accessTags jerarquía de etiquetas de acceso de OSM por modo, de la más específica a la más general
+var accessTags = map[AccessMask][]string{
+	AccessCar:        {"motorcar", "motor_vehicle", "vehicle", "access"},
+	AccessMotorcycle: {"motorcycle", "motor_vehicle", "vehicle", "access"},
+	AccessTruck:      {"hgv", "goods", "motor_vehicle", "vehicle", "access"},
+	AccessBicycle:    {"bicycle", "vehicle", "access"},
+	AccessFoot:       {"foot", "access"},
+}
+
+// unpavedSurfaces valores de la etiqueta surface que indican una vía sin pavimentar
+var unpavedSurfaces = map[string]bool{
+	"unpaved":     true,
+	"compacted":   true,
+	"dirt":        true,
+	"earth":       true,
+	"fine_gravel": true,
+	"grass":       true,
+	"gravel":      true,
+	"ground":      true,
+	"mud":         true,
+	"pebblestone": true,
+	"rock":        true,
+	"sand":        true,
+	"woodchips":   true,
+}
+
+// osmWay vía de OSM con sus referencias a nodos y etiquetas
+type osmWay struct {
+	ID    int64
+	Nodes []int64
+	Tags  map[string]string
+}
+
+// osmData datos crudos leídos de un extracto OSM
+type osmData struct {
+	coords map[int64][2]float64
+	ways   []osmWay
+}
+
+func newOSMData() *osmData {
+	return &osmData{
+		coords: make(map[int64][2]float64),
+	}
+}
+
+// addWay guarda la vía solo si es una vía transitable
+func (d *osmData) addWay(way osmWay) {
+	if _, ok := classAccess[RoadClass(way.Tags["highway"])]; ok && len(way.Nodes) > 1 {
+		d.ways = append(d.ways, way)
+	}
+}
+
+// LoadOSM lee un extracto de OpenStreetMap (.osm, .osm.gz, .xml o .pbf) y construye la red vial
+func LoadOSM(path string) (*Graph, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo extracto OSM: %w", err)
+	}
+	defer file.Close()
+
+	var data *osmData
+	switch {
+	case strings.HasSuffix(path, ".pbf"):
+		data, err = readPBF(file)
+	case strings.HasSuffix(path, ".gz"):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(file); err == nil {
+			data, err = readXML(gz)
+			gz.Close()
+		}
+	default:
+		data, err = readXML(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo extracto OSM %s: %w", path, err)
+	}
+
+	return buildGraph(data), nil
+}
+
+// ReadOSMXML construye la red vial a partir de un documento OSM XML
+func ReadOSMXML(r io.Reader) (*Graph, error) {
+	data, err := readXML(r)
+	if err != nil {
+		return nil, err
+	}
+	return buildGraph(data), nil
+}
+
+// buildGraph convierte vías OSM en tramos dirigidos entre nodos consecutivos
+func buildGraph(data *osmData) *Graph {
+	g := NewGraph()
+
+	for _, way := range data.ways {
+		class := RoadClass(way.Tags["highway"])
+		access := wayAccess(way.Tags, class)
+		if access == 0 {
+			continue
+		}
+
+		// Velocidad vehicular: límite señalizado o velocidad típica de la clase
+		speed := parseMaxSpeed(way.Tags["maxspeed"])
+		if speed <= 0 {
+			speed = defaultSpeeds[class]
+		}
+
+		// Los peatones (y ciclistas si está permitido) circulan en ambos sentidos
+		forward, backward := onewayDirections(way.Tags)
+		contraflow := access & AccessFoot
+		if way.Tags["oneway:bicycle"] == "no" || strings.HasPrefix(way.Tags["cycleway"], "opposite") {
+			contraflow |= access & AccessBicycle
+		}
+		forwardAccess, backwardAccess := access, access
+		if !forward {
+			forwardAccess = contraflow
+		}
+		if !backward {
+			backwardAccess = contraflow
+		}
+
+		roundabout := way.Tags["junction"] == "roundabout" || way.Tags["junction"] == "circular"
+		maxHeight := parseDimension(way.Tags["maxheight"], "ft", 0.3048)
+		maxWeight := parseDimension(way.Tags["maxweight"], "st", 0.907185)
+		toll := way.Tags["toll"] == "yes"
+		unpaved := isUnpaved(way.Tags, class)
+		name := way.Tags["name"]
+		if name == "" {
+			name = way.Tags["ref"]
+		}
+
+		prev := -1
+		for _, ref := range way.Nodes {
+			coord, ok := data.coords[ref]
+			if !ok {
+				// Nodo fuera del extracto: cortar la vía en este punto
+				prev = -1
+				continue
+			}
+			idx := g.AddNode(ref, coord[0], coord[1])
+			if prev >= 0 && prev != idx {
+				edge := Edge{
+					From:       prev,
+					To:         idx,
+					Speed:      speed,
+					Name:       name,
+					Class:      class,
+					WayID:      way.ID,
+					Roundabout: roundabout,
+					MaxHeight:  maxHeight,
+					MaxWeight:  maxWeight,
+					Toll:       toll,
+					Unpaved:    unpaved,
+				}
+				if forwardAccess != 0 {
+					edge.Access = forwardAccess
+					g.AddEdge(edge)
+				}
+				if backwardAccess != 0 {
+					edge.From, edge.To = idx, prev
+					edge.Access = backwardAccess
+					g.AddEdge(edge)
+				}
+			}
+			prev = idx
+		}
+	}
+
+	g.Build()
+	return g
+}
+
+// wayAccess calcula los modos de transporte permitidos en una vía a partir de
+// los valores por defecto de su clase y de las etiquetas de acceso
+func wayAccess(tags map[string]string, class RoadClass) AccessMask {
+	if tags["area"] == "yes" {
+		return 0
+	}
+
+	defaults := classAccess[class]
+	var access AccessMask
+	for mode, keys := range accessTags {
+		allowed := defaults&mode != 0
+	tags:
+		for _, key := range keys {
+			switch tags[key] {
+			case "no", "private":
+				allowed = false
+				break tags
+			case "yes", "designated", "permissive", "destination":
+				allowed = true
+				break tags
+			}
+		}
+		if allowed {
+			access |= mode
+		}
+	}
+	return access
+}
+
+// isUnpaved indica si una vía no está pavimentada según su superficie. Los caminos
+// rurales (track) sin superficie conocida se consideran sin pavimentar salvo grade1
+func isUnpaved(tags map[string]string, class RoadClass) bool {
+	if surface := tags["surface"]; surface != "" {
+		return unpavedSurfaces[surface] || strings.HasPrefix(surface, "unpaved")
+	}
+	return class == "track" && tags["tracktype"] != "grade1"
+}
+
+// onewayDirections determina los sentidos de circulación permitidos de una vía
+func onewayDirections(tags map[string]string) (forward, backward bool) {
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		return true, false
+	case "-1", "reverse":
+		return false, true
+	case "no", "false", "0":
+		return true, true
+	}
+
+	// Sentido único implícito en rotondas, autopistas y sus enlaces
+	if tags["junction"] == "roundabout" || tags["junction"] == "circular" ||
+		tags["highway"] == "motorway" || tags["highway"] == "motorway_link" {
+		return true, false
+	}
+	return true, true
+}
+
+// parseMaxSpeed interpreta la etiqueta maxspeed ("60", "60 km/h", "35 mph"). Retorna 0 si no es numérica
+func parseMaxSpeed(value string) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	end := 0
+	for end < len(value) && (value[end] >= '0' && value[end] <= '9' || value[end] == '.') {
+		end++
+	}
+	speed, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0
+	}
+
+	if strings.Contains(value[end:], "mph") {
+		speed *= 1.609344
+	}
+	return speed
+}
+
+// parseDimension interpreta etiquetas de dimensión como maxheight o maxweight ("4.5", "4.5 m", "12 t").
+// Si el valor trae la unidad alternativa se convierte con factor. Retorna 0 si no es numérica
+func parseDimension(value, altUnit string, factor float64) float64 {
+	value = strings.TrimSpace(value)
+	end := 0
+	for end < len(value) && (value[end] >= '0' && value[end] <= '9' || value[end] == '.') {
+		end++
+	}
+	n, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0
+	}
+
+	rest := strings.TrimSpace(value[end:])
+	switch {
+	case strings.HasPrefix(rest, "'"):
+		// Pies y pulgadas: 13'6"
+		inches, _ := strconv.ParseFloat(strings.Trim(rest[1:], "\" "), 64)
+		return (n*12 + inches) * 0.0254
+	case strings.HasPrefix(rest, altUnit):
+		return n * factor
+	case strings.HasPrefix(rest, "kg"):
+		return n / 1000
+	}
+	return n
+}
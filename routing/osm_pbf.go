@@ -0,0 +1,522 @@
+package routing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Límites del formato PBF según la especificación de OSM
+const (
+	maxBlobHeaderSize = 64 * 1024
+	maxBlobSize       = 32 * 1024 * 1024
+)
+
+// supportedPBFFeatures características requeridas que el lector soporta
+var supportedPBFFeatures = map[string]bool{
+	"OsmSchema-V0.6": true,
+	"DenseNodes":     true,
+}
+
+// readPBF lee un archivo OSM PBF decodificando manualmente los mensajes protobuf
+func readPBF(r io.Reader) (*osmData, error) {
+	data := newOSMData()
+	var sizeBuf [4]byte
+
+	for {
+		if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+			if err == io.EOF {
+				return data, nil
+			}
+			return nil, err
+		}
+
+		headerSize := binary.BigEndian.Uint32(sizeBuf[:])
+		if headerSize > maxBlobHeaderSize {
+			return nil, fmt.Errorf("BlobHeader demasiado grande: %d bytes", headerSize)
+		}
+		headerBuf := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, headerBuf); err != nil {
+			return nil, err
+		}
+
+		blobType, blobSize, err := parseBlobHeader(headerBuf)
+		if err != nil {
+			return nil, err
+		}
+		if blobSize < 0 || blobSize > maxBlobSize {
+			return nil, fmt.Errorf("Blob demasiado grande: %d bytes", blobSize)
+		}
+		blobBuf := make([]byte, blobSize)
+		if _, err := io.ReadFull(r, blobBuf); err != nil {
+			return nil, err
+		}
+
+		block, err := decodeBlob(blobBuf)
+		if err != nil {
+			return nil, err
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			if err := checkHeaderBlock(block); err != nil {
+				return nil, err
+			}
+		case "OSMData":
+			if err := parsePrimitiveBlock(block, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// parseBlobHeader extrae el tipo y el tamaño del Blob siguiente
+func parseBlobHeader(buf []byte) (string, int, error) {
+	var blobType string
+	var size int
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return "", 0, err
+		}
+		switch {
+		case field == 1 && wire == 2:
+			b, err := pb.bytes()
+			if err != nil {
+				return "", 0, err
+			}
+			blobType = string(b)
+		case field == 3 && wire == 0:
+			v, err := pb.varint()
+			if err != nil {
+				return "", 0, err
+			}
+			// Comprobar antes de convertir: un valor enorme daría un int negativo
+			if v > maxBlobSize {
+				return "", 0, fmt.Errorf("Blob demasiado grande: %d bytes", v)
+			}
+			size = int(v)
+		default:
+			if err := pb.skip(wire); err != nil {
+				return "", 0, err
+			}
+		}
+	}
+	return blobType, size, nil
+}
+
+// decodeBlob retorna el contenido descomprimido de un Blob
+func decodeBlob(buf []byte) ([]byte, error) {
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wire == 2: // raw
+			return pb.bytes()
+		case field == 3 && wire == 2: // zlib_data
+			compressed, err := pb.bytes()
+			if err != nil {
+				return nil, err
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			// Limitar la salida para que un Blob muy comprimible no agote la memoria
+			raw, err := io.ReadAll(io.LimitReader(zr, maxBlobSize+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(raw) > maxBlobSize {
+				return nil, fmt.Errorf("Blob descomprimido demasiado grande: más de %d bytes", maxBlobSize)
+			}
+			return raw, nil
+		case wire == 2 && field >= 4:
+			return nil, fmt.Errorf("compresión de Blob no soportada (campo %d)", field)
+		default:
+			if err := pb.skip(wire); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, errors.New("Blob sin datos")
+}
+
+// checkHeaderBlock verifica que el archivo no requiera características no soportadas
+func checkHeaderBlock(buf []byte) error {
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return err
+		}
+		if field == 4 && wire == 2 {
+			b, err := pb.bytes()
+			if err != nil {
+				return err
+			}
+			if !supportedPBFFeatures[string(b)] {
+				return fmt.Errorf("característica PBF no soportada: %s", b)
+			}
+			continue
+		}
+		if err := pb.skip(wire); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// primitiveBlock parámetros de un PrimitiveBlock necesarios para decodificar sus grupos
+type primitiveBlock struct {
+	strings     []string
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *primitiveBlock) coord(lat, lon int64) [2]float64 {
+	return [2]float64{
+		1e-9 * float64(b.latOffset+b.granularity*lat),
+		1e-9 * float64(b.lonOffset+b.granularity*lon),
+	}
+}
+
+// parsePrimitiveBlock decodifica nodos y vías de un bloque OSMData
+func parsePrimitiveBlock(buf []byte, data *osmData) error {
+	block := primitiveBlock{granularity: 100}
+	var groups [][]byte
+
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wire == 2:
+			st, err := pb.bytes()
+			if err != nil {
+				return err
+			}
+			if block.strings, err = parseStringTable(st); err != nil {
+				return err
+			}
+		case field == 2 && wire == 2:
+			group, err := pb.bytes()
+			if err != nil {
+				return err
+			}
+			groups = append(groups, group)
+		case field == 17 && wire == 0:
+			v, err := pb.varint()
+			if err != nil {
+				return err
+			}
+			block.granularity = int64(v)
+		case field == 19 && wire == 0:
+			v, err := pb.varint()
+			if err != nil {
+				return err
+			}
+			block.latOffset = int64(v)
+		case field == 20 && wire == 0:
+			v, err := pb.varint()
+			if err != nil {
+				return err
+			}
+			block.lonOffset = int64(v)
+		default:
+			if err := pb.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Los grupos se procesan al final porque la tabla de strings puede venir después
+	for _, group := range groups {
+		if err := parsePrimitiveGroup(group, &block, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseStringTable(buf []byte) ([]string, error) {
+	var table []string
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return nil, err
+		}
+		if field == 1 && wire == 2 {
+			s, err := pb.bytes()
+			if err != nil {
+				return nil, err
+			}
+			table = append(table, string(s))
+			continue
+		}
+		if err := pb.skip(wire); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+func parsePrimitiveGroup(buf []byte, block *primitiveBlock, data *osmData) error {
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return err
+		}
+		if wire != 2 {
+			if err := pb.skip(wire); err != nil {
+				return err
+			}
+			continue
+		}
+
+		msg, err := pb.bytes()
+		if err != nil {
+			return err
+		}
+		switch field {
+		case 1:
+			err = parseNode(msg, block, data)
+		case 2:
+			err = parseDenseNodes(msg, block, data)
+		case 3:
+			err = parseWay(msg, block, data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseNode(buf []byte, block *primitiveBlock, data *osmData) error {
+	var id, lat, lon int64
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wire == 0:
+			id, err = pb.sint()
+		case field == 8 && wire == 0:
+			lat, err = pb.sint()
+		case field == 9 && wire == 0:
+			lon, err = pb.sint()
+		default:
+			err = pb.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	data.coords[id] = block.coord(lat, lon)
+	return nil
+}
+
+func parseDenseNodes(buf []byte, block *primitiveBlock, data *osmData) error {
+	var ids, lats, lons []int64
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1:
+			ids, err = pb.packedSint(wire, ids)
+		case field == 8:
+			lats, err = pb.packedSint(wire, lats)
+		case field == 9:
+			lons, err = pb.packedSint(wire, lons)
+		default:
+			err = pb.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ids) != len(lats) || len(ids) != len(lons) {
+		return errors.New("DenseNodes con longitudes inconsistentes")
+	}
+
+	// Los valores vienen codificados como deltas
+	var id, lat, lon int64
+	for i := range ids {
+		id += ids[i]
+		lat += lats[i]
+		lon += lons[i]
+		data.coords[id] = block.coord(lat, lon)
+	}
+	return nil
+}
+
+func parseWay(buf []byte, block *primitiveBlock, data *osmData) error {
+	var keys, vals []uint64
+	var refs []int64
+	way := osmWay{Tags: make(map[string]string)}
+
+	pb := pbReader{buf: buf}
+	for pb.more() {
+		field, wire, err := pb.key()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wire == 0:
+			var v uint64
+			v, err = pb.varint()
+			way.ID = int64(v)
+		case field == 2:
+			keys, err = pb.packedVarint(wire, keys)
+		case field == 3:
+			vals, err = pb.packedVarint(wire, vals)
+		case field == 8:
+			refs, err = pb.packedSint(wire, refs)
+		default:
+			err = pb.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range keys {
+		if i >= len(vals) || int(keys[i]) >= len(block.strings) || int(vals[i]) >= len(block.strings) {
+			return errors.New("Way con etiquetas fuera de la tabla de strings")
+		}
+		way.Tags[block.strings[keys[i]]] = block.strings[vals[i]]
+	}
+
+	var ref int64
+	way.Nodes = make([]int64, len(refs))
+	for i, delta := range refs {
+		ref += delta
+		way.Nodes[i] = ref
+	}
+
+	data.addWay(way)
+	return nil
+}
+
+// pbReader lector mínimo de mensajes protobuf
+type pbReader struct {
+	buf []byte
+	pos int
+}
+
+var errTruncated = errors.New("mensaje protobuf truncado")
+
+func (pb *pbReader) more() bool {
+	return pb.pos < len(pb.buf)
+}
+
+func (pb *pbReader) key() (int, int, error) {
+	v, err := pb.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(v >> 3), int(v & 7), nil
+}
+
+func (pb *pbReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(pb.buf[pb.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	pb.pos += n
+	return v, nil
+}
+
+func (pb *pbReader) sint() (int64, error) {
+	v, err := pb.varint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (pb *pbReader) bytes() ([]byte, error) {
+	n, err := pb.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(pb.buf)-pb.pos) < n {
+		return nil, errTruncated
+	}
+	b := pb.buf[pb.pos : pb.pos+int(n)]
+	pb.pos += int(n)
+	return b, nil
+}
+
+func (pb *pbReader) skip(wire int) error {
+	var n int
+	switch wire {
+	case 0:
+		_, err := pb.varint()
+		return err
+	case 1:
+		n = 8
+	case 2:
+		_, err := pb.bytes()
+		return err
+	case 5:
+		n = 4
+	default:
+		return fmt.Errorf("tipo de campo protobuf no soportado: %d", wire)
+	}
+	if len(pb.buf)-pb.pos < n {
+		return errTruncated
+	}
+	pb.pos += n
+	return nil
+}
+
+// packedVarint lee un campo repetido de varints, empaquetado o no
+func (pb *pbReader) packedVarint(wire int, dst []uint64) ([]uint64, error) {
+	if wire == 0 {
+		v, err := pb.varint()
+		return append(dst, v), err
+	}
+	b, err := pb.bytes()
+	if err != nil {
+		return dst, err
+	}
+	inner := pbReader{buf: b}
+	for inner.more() {
+		v, err := inner.varint()
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, v)
+	}
+	return dst, nil
+}
+
+// packedSint lee un campo repetido de sint64 (zigzag), empaquetado o no
+func (pb *pbReader) packedSint(wire int, dst []int64) ([]int64, error) {
+	raw, err := pb.packedVarint(wire, nil)
+	if err != nil {
+		return dst, err
+	}
+	for _, v := range raw {
+		dst = append(dst, int64(v>>1)^-int64(v&1))
+	}
+	return dst, nil
+}
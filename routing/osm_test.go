@@ -0,0 +1,363 @@
+package routing
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fixtureNodes nodos del extracto de prueba
+var fixtureNodes = map[int64][2]float64{
+	1: {14.0800001, -87.2100003},
+	2: {14.081, -87.21},
+	3: {14.082, -87.209},
+	4: {14.083, -87.208},
+	5: {14.084, -87.208},
+	6: {14.085, -87.2069999},
+	7: {14.086, -87.207},
+}
+
+// fixtureWays vías del extracto de prueba: una primaria con límite, una residencial de
+// sentido único, un sendero peatonal, un edificio, un camino de servicio con un nodo
+// fuera del extracto, una vía sin acceso, un área peatonal y un enlace de autopista,
+// de sentido único implícito
+var fixtureWays = []osmWay{
+	{ID: 10, Nodes: []int64{1, 2, 3}, Tags: map[string]string{"highway": "primary", "name": "Bulevar del Norte", "maxspeed": "80"}},
+	{ID: 11, Nodes: []int64{3, 4}, Tags: map[string]string{"highway": "residential", "name": "Calle 1", "oneway": "yes"}},
+	{ID: 12, Nodes: []int64{4, 5}, Tags: map[string]string{"highway": "footway"}},
+	{ID: 13, Nodes: []int64{1, 2, 5, 1}, Tags: map[string]string{"building": "yes"}},
+	{ID: 14, Nodes: []int64{4, 99, 6, 1}, Tags: map[string]string{"highway": "service", "ref": "S1"}},
+	{ID: 15, Nodes: []int64{5, 6}, Tags: map[string]string{"highway": "residential", "access": "no"}},
+	{ID: 16, Nodes: []int64{5, 6, 1, 5}, Tags: map[string]string{"highway": "pedestrian", "area": "yes"}},
+	{ID: 17, Nodes: []int64{6, 7}, Tags: map[string]string{"highway": "motorway_link"}},
+}
+
+// edgeSummary atributos de un tramo importado, identificado por los IDs OSM de sus nodos
+type edgeSummary struct {
+	From, To int64
+	Class    RoadClass
+	Speed    float64
+	Name     string
+	WayID    int64
+	Access   AccessMask
+}
+
+var fixtureEdges = []edgeSummary{
+	{1, 2, "primary", 80, "Bulevar del Norte", 10, accessAll},
+	{1, 6, "service", 20, "S1", 14, accessAll},
+	{2, 1, "primary", 80, "Bulevar del Norte", 10, accessAll},
+	{2, 3, "primary", 80, "Bulevar del Norte", 10, accessAll},
+	{3, 2, "primary", 80, "Bulevar del Norte", 10, accessAll},
+	{3, 4, "residential", 30, "Calle 1", 11, accessAll},
+	{4, 3, "residential", 30, "Calle 1", 11, AccessFoot},
+	{4, 5, "footway", 0, "", 12, AccessFoot},
+	{5, 4, "footway", 0, "", 12, AccessFoot},
+	{6, 1, "service", 20, "S1", 14, accessAll},
+	{6, 7, "motorway_link", 60, "", 17, accessMotor},
+}
+
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedNodeIDs() []int64 {
+	ids := make([]int64, 0, len(fixtureNodes))
+	for id := range fixtureNodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// fixtureXML el extracto de prueba en formato OSM XML
+func fixtureXML() []byte {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<osm version=\"0.6\">\n")
+	for _, id := range sortedNodeIDs() {
+		c := fixtureNodes[id]
+		fmt.Fprintf(&b, "  <node id=\"%d\" lat=\"%.7f\" lon=\"%.7f\"/>\n", id, c[0], c[1])
+	}
+	for _, way := range fixtureWays {
+		fmt.Fprintf(&b, "  <way id=\"%d\">\n", way.ID)
+		for _, ref := range way.Nodes {
+			fmt.Fprintf(&b, "    <nd ref=\"%d\"/>\n", ref)
+		}
+		for _, k := range sortedKeys(way.Tags) {
+			fmt.Fprintf(&b, "    <tag k=\"%s\" v=\"%s\"/>\n", k, way.Tags[k])
+		}
+		b.WriteString("  </way>\n")
+	}
+	b.WriteString("</osm>\n")
+	return []byte(b.String())
+}
+
+func gzipped(data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+	return buf.Bytes()
+}
+
+// pbWriter escritor mínimo de mensajes protobuf, el inverso de pbReader
+type pbWriter struct {
+	buf []byte
+}
+
+func (w *pbWriter) key(field, wire int) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(field<<3|wire))
+}
+
+func (w *pbWriter) varint(field int, v uint64) {
+	w.key(field, 0)
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *pbWriter) sint(field int, v int64) {
+	w.varint(field, uint64(v<<1^v>>63))
+}
+
+func (w *pbWriter) bytes(field int, b []byte) {
+	w.key(field, 2)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *pbWriter) packedVarint(field int, values []uint64) {
+	var packed []byte
+	for _, v := range values {
+		packed = binary.AppendUvarint(packed, v)
+	}
+	w.bytes(field, packed)
+}
+
+// packedSint escribe valores como deltas codificados en zigzag
+func (w *pbWriter) packedSint(field int, values []int64) {
+	var packed []byte
+	var prev int64
+	for _, v := range values {
+		d := v - prev
+		packed = binary.AppendUvarint(packed, uint64(d<<1^d>>63))
+		prev = v
+	}
+	w.bytes(field, packed)
+}
+
+// fixturePBF el extracto de prueba en formato PBF. Todos los nodos salvo el último van
+// como DenseNodes y el último como Node simple. Con compress los Blobs usan zlib
+func fixturePBF(compress bool) []byte {
+	var header pbWriter
+	header.bytes(4, []byte("OsmSchema-V0.6"))
+	header.bytes(4, []byte("DenseNodes"))
+
+	// La tabla de strings empieza con la cadena vacía, como en los archivos reales
+	table := []string{""}
+	index := map[string]uint64{}
+	str := func(s string) uint64 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = uint64(len(table))
+		table = append(table, s)
+		return index[s]
+	}
+
+	var nodes pbWriter
+	ids := sortedNodeIDs()
+	var dense pbWriter
+	var denseIDs, lats, lons []int64
+	for _, id := range ids[:len(ids)-1] {
+		c := fixtureNodes[id]
+		denseIDs = append(denseIDs, id)
+		lats = append(lats, int64(math.Round(c[0]*1e7)))
+		lons = append(lons, int64(math.Round(c[1]*1e7)))
+	}
+	dense.packedSint(1, denseIDs)
+	dense.packedSint(8, lats)
+	dense.packedSint(9, lons)
+	nodes.bytes(2, dense.buf)
+
+	var node pbWriter
+	last := ids[len(ids)-1]
+	node.sint(1, last)
+	node.sint(8, int64(math.Round(fixtureNodes[last][0]*1e7)))
+	node.sint(9, int64(math.Round(fixtureNodes[last][1]*1e7)))
+	nodes.bytes(1, node.buf)
+
+	var ways pbWriter
+	for _, way := range fixtureWays {
+		var keys, vals []uint64
+		for _, k := range sortedKeys(way.Tags) {
+			keys = append(keys, str(k))
+			vals = append(vals, str(way.Tags[k]))
+		}
+		var w pbWriter
+		w.varint(1, uint64(way.ID))
+		w.packedVarint(2, keys)
+		w.packedVarint(3, vals)
+		w.packedSint(8, way.Nodes)
+		ways.bytes(3, w.buf)
+	}
+
+	var st pbWriter
+	for _, s := range table {
+		st.bytes(1, []byte(s))
+	}
+	var block pbWriter
+	block.bytes(1, st.buf)
+	block.bytes(2, nodes.buf)
+	block.bytes(2, ways.buf)
+	block.varint(17, 100)
+
+	var file bytes.Buffer
+	writeBlob := func(blobType string, data []byte) {
+		var blob pbWriter
+		if compress {
+			var zbuf bytes.Buffer
+			zw := zlib.NewWriter(&zbuf)
+			zw.Write(data)
+			zw.Close()
+			blob.varint(2, uint64(len(data)))
+			blob.bytes(3, zbuf.Bytes())
+		} else {
+			blob.bytes(1, data)
+		}
+		var bh pbWriter
+		bh.bytes(1, []byte(blobType))
+		bh.varint(3, uint64(len(blob.buf)))
+
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(bh.buf)))
+		file.Write(size[:])
+		file.Write(bh.buf)
+		file.Write(blob.buf)
+	}
+	writeBlob("OSMHeader", header.buf)
+	writeBlob("OSMData", block.buf)
+	return file.Bytes()
+}
+
+func TestLoadOSM(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data []byte
+	}{
+		{"XML", "extracto.osm", fixtureXML()},
+		{"XML comprimido", "extracto.osm.gz", gzipped(fixtureXML())},
+		{"PBF", "extracto.osm.pbf", fixturePBF(false)},
+		{"PBF con zlib", "extracto.osm.pbf", fixturePBF(true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			g, err := LoadOSM(path)
+			if err != nil {
+				t.Fatalf("LoadOSM: %v", err)
+			}
+
+			// Solo se agregan los nodos de las vías importadas
+			if len(g.Nodes) != len(fixtureNodes) {
+				t.Errorf("%d nodos, esperados %d", len(g.Nodes), len(fixtureNodes))
+			}
+			for _, n := range g.Nodes {
+				want, ok := fixtureNodes[n.ID]
+				if !ok || math.Abs(n.Lat-want[0]) > 1e-9 || math.Abs(n.Lng-want[1]) > 1e-9 {
+					t.Errorf("nodo %d en (%v, %v), esperado %v", n.ID, n.Lat, n.Lng, want)
+				}
+			}
+
+			edges := make([]edgeSummary, len(g.Edges))
+			for i, e := range g.Edges {
+				if e.Length <= 0 {
+					t.Errorf("tramo %d sin longitud", i)
+				}
+				edges[i] = edgeSummary{
+					From:   g.Nodes[e.From].ID,
+					To:     g.Nodes[e.To].ID,
+					Class:  e.Class,
+					Speed:  e.Speed,
+					Name:   e.Name,
+					WayID:  e.WayID,
+					Access: e.Access,
+				}
+			}
+			sort.Slice(edges, func(i, j int) bool {
+				if edges[i].From != edges[j].From {
+					return edges[i].From < edges[j].From
+				}
+				return edges[i].To < edges[j].To
+			})
+			if !reflect.DeepEqual(edges, fixtureEdges) {
+				t.Errorf("tramos importados:\n%v\nesperados:\n%v", edges, fixtureEdges)
+			}
+		})
+	}
+}
+
+// pbfBlock un bloque PBF: BlobHeader con el tipo y el tamaño indicado, seguido del Blob
+func pbfBlock(blobType string, datasize uint64, blob []byte) []byte {
+	var bh pbWriter
+	bh.bytes(1, []byte(blobType))
+	bh.varint(3, datasize)
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(bh.buf)))
+	return append(append(data, bh.buf...), blob...)
+}
+
+func TestLoadOSMInvalidPBF(t *testing.T) {
+	var unsupported, header, raw pbWriter
+	header.bytes(4, []byte("HistoricalInformation"))
+	unsupported.bytes(1, header.buf)
+
+	// Un bloque de ceros que descomprimido supera maxBlobSize
+	var zbuf bytes.Buffer
+	zw := zlib.NewWriter(&zbuf)
+	zw.Write(make([]byte, maxBlobSize+1))
+	zw.Close()
+	var bomb pbWriter
+	bomb.varint(2, maxBlobSize+1)
+	bomb.bytes(3, zbuf.Bytes())
+
+	raw.bytes(1, []byte{0})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"característica no soportada", pbfBlock("OSMHeader", uint64(len(unsupported.buf)), unsupported.buf), "HistoricalInformation"},
+		{"tamaño de Blob enorme", pbfBlock("OSMData", math.MaxUint64, raw.buf), "demasiado grande"},
+		{"tamaño de Blob sobre el máximo", pbfBlock("OSMData", maxBlobSize+1, raw.buf), "demasiado grande"},
+		{"Blob comprimido que excede el máximo", pbfBlock("OSMData", uint64(len(bomb.buf)), bomb.buf), "descomprimido demasiado grande"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "invalido.osm.pbf")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadOSM(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadOSM retornó %v, esperado un error con %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// readXML lee un documento OSM XML en streaming
+func readXML(r io.Reader) (*osmData, error) {
+	data := newOSMData()
+	decoder := xml.NewDecoder(r)
+
+	var way *osmWay
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch el := token.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "node":
+				id, lat, lng := xmlAttr(el, "id"), xmlAttr(el, "lat"), xmlAttr(el, "lon")
+				nodeID, err := strconv.ParseInt(id, 10, 64)
+				if err != nil {
+					continue
+				}
+				latF, errLat := strconv.ParseFloat(lat, 64)
+				lngF, errLng := strconv.ParseFloat(lng, 64)
+				if errLat == nil && errLng == nil {
+					data.coords[nodeID] = [2]float64{latF, lngF}
+				}
+			case "way":
+				id, _ := strconv.ParseInt(xmlAttr(el, "id"), 10, 64)
+				way = &osmWay{ID: id, Tags: make(map[string]string)}
+			case "nd":
+				if way != nil {
+					if ref, err := strconv.ParseInt(xmlAttr(el, "ref"), 10, 64); err == nil {
+						way.Nodes = append(way.Nodes, ref)
+					}
+				}
+			case "tag":
+				if way != nil {
+					way.Tags[xmlAttr(el, "k")] = xmlAttr(el, "v")
+				}
+			}
+		case xml.EndElement:
+			if el.Name.Local == "way" && way != nil {
+				data.addWay(*way)
+				way = nil
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// xmlAttr retorna el valor de un atributo o cadena vacía
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
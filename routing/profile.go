@@ -0,0 +1,161 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccessMask modos de transporte habilitados en un tramo
+type AccessMask uint8
+
+// Modos de transporte
+const (
+	AccessCar AccessMask = 1 << iota
+	AccessMotorcycle
+	AccessTruck
+	AccessBicycle
+	AccessFoot
+
+	accessMotor = AccessCar | AccessMotorcycle | AccessTruck
+	accessAll   = accessMotor | AccessBicycle | AccessFoot
+)
+
+// Profile define qué tramos puede usar un modo de viaje y a qué velocidad
+type Profile struct {
+	Name         string                `json:"name"`
+	Access       AccessMask            `json:"-"`
+	Speeds       map[RoadClass]float64 `json:"-"`                // velocidad por clase; limita la velocidad del tramo
+	MaxSpeed     float64               `json:"max_speed"`        // velocidad máxima del vehículo en km/h (0 = sin límite)
+	TypicalSpeed float64               `json:"typical_speed"`    // velocidad promedio para estimaciones sin red vial
+	Motorized    bool                  `json:"motorized"`        // afectado por el tráfico vehicular
+	Height       float64               `json:"height,omitempty"` // altura del vehículo en metros
+	Weight       float64               `json:"weight,omitempty"` // peso del vehículo en toneladas
+}
+
+// profiles perfiles disponibles por nombre
+var profiles = map[string]Profile{
+	"car": {
+		Name:         "car",
+		Access:       AccessCar,
+		Speeds:       map[RoadClass]float64{"track": 15},
+		TypicalSpeed: 50,
+		Motorized:    true,
+	},
+	"motorcycle": {
+		Name:         "motorcycle",
+		Access:       AccessMotorcycle,
+		Speeds:       map[RoadClass]float64{"track": 20},
+		MaxSpeed:     110,
+		TypicalSpeed: 50,
+		Motorized:    true,
+	},
+	"truck": {
+		Name:   "truck",
+		Access: AccessTruck,
+		Speeds: map[RoadClass]float64{
+			"residential":   25,
+			"living_street": 10,
+			"service":       15,
+			"track":         10,
+		},
+		MaxSpeed:     80,
+		TypicalSpeed: 40,
+		Motorized:    true,
+	},
+	"bicycle": {
+		Name:   "bicycle",
+		Access: AccessBicycle,
+		Speeds: map[RoadClass]float64{
+			"trunk": 16, "trunk_link": 16, "primary": 16, "primary_link": 16,
+			"secondary": 16, "secondary_link": 16, "tertiary": 16, "tertiary_link": 16,
+			"unclassified": 16, "residential": 16, "road": 16, "living_street": 12,
+			"service": 14, "track": 10, "cycleway": 18, "path": 12,
+		},
+		MaxSpeed:     20,
+		TypicalSpeed: 15,
+	},
+	"walking": {
+		Name:   "walking",
+		Access: AccessFoot,
+		Speeds: map[RoadClass]float64{
+			"trunk": 5, "trunk_link": 5, "primary": 5, "primary_link": 5,
+			"secondary": 5, "secondary_link": 5, "tertiary": 5, "tertiary_link": 5,
+			"unclassified": 5, "residential": 5, "road": 5, "living_street": 5,
+			"service": 5, "track": 4.5, "footway": 5, "pedestrian": 5, "path": 4.5,
+			"steps": 2.5,
+		},
+		MaxSpeed:     5,
+		TypicalSpeed: 5,
+	},
+}
+
+// DefaultProfile perfil usado cuando no se especifica uno
+const DefaultProfile = "car"
+
+// ProfileByName retorna una copia del perfil solicitado
+func ProfileByName(name string) (*Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultProfile
+	}
+	switch name {
+	case "foot", "pedestrian":
+		name = "walking"
+	case "bike":
+		name = "bicycle"
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("perfil desconocido: %s", name)
+	}
+	return &profile, nil
+}
+
+// ProfileNames retorna los nombres de los perfiles disponibles
+func ProfileNames() []string {
+	return []string{"car", "motorcycle", "truck", "bicycle", "walking"}
+}
+
+// Allows indica si el perfil puede recorrer un tramo
+func (p *Profile) Allows(e *Edge) bool {
+	if e.Access&p.Access == 0 {
+		return false
+	}
+	if p.Height > 0 && e.MaxHeight > 0 && p.Height > e.MaxHeight {
+		return false
+	}
+	if p.Weight > 0 && e.MaxWeight > 0 && p.Weight > e.MaxWeight {
+		return false
+	}
+	return true
+}
+
+// EdgeSpeed retorna la velocidad de flujo libre del perfil sobre un tramo, o 0 si no puede usarlo
+func (p *Profile) EdgeSpeed(e *Edge) float64 {
+	if !p.Allows(e) {
+		return 0
+	}
+
+	speed := e.Speed
+	if s, ok := p.Speeds[e.Class]; ok && (speed <= 0 || s < speed) {
+		speed = s
+	}
+	if p.MaxSpeed > 0 && speed > p.MaxSpeed {
+		speed = p.MaxSpeed
+	}
+	return speed
+}
+
+// Weighting retorna el peso de tiempo de viaje del perfil, con tráfico en vivo
+// para los perfiles motorizados. traffic puede ser nil
+func (r *Router) Weighting(profile *Profile, traffic *Traffic) Weighting {
+	return func(edge int, elapsed float64) float64 {
+		e := &r.graph.Edges[edge]
+		speed := profile.EdgeSpeed(e)
+		if profile.Motorized {
+			speed = traffic.Apply(edge, speed)
+		}
+		return travelTime(e.Length, speed)
+	}
+}
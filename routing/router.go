@@ -0,0 +1,300 @@
+package routing
+
+import (
+	"container/heap"
+	"errors"
+	"gowaze/models"
+	"gowaze/utils"
+	"math"
+	"sync"
+)
+
+var (
+	// ErrNoGraph indica que no hay red vial cargada
+	ErrNoGraph = errors.New("no hay red vial cargada")
+	// ErrNoSnap indica que un punto está demasiado lejos de la red vial
+	ErrNoSnap = errors.New("punto fuera de la red vial")
+	// ErrNoRoute indica que no existe un camino entre los puntos
+	ErrNoRoute = errors.New("no existe ruta entre los puntos")
+)
+
+// DefaultMaxSnapDistance distancia máxima en km para ubicar un punto sobre la red
+const DefaultMaxSnapDistance = 0.5
+
+// Weighting calcula el costo en segundos de recorrer un tramo completo, dado el
+// tiempo transcurrido desde la salida. Un costo infinito marca el tramo como intransitable
+type Weighting func(edge int, elapsed float64) float64
+
+// Segment es una porción de tramo recorrida por una ruta
+type Segment struct {
+	Edge     int     `json:"edge"`
+	Distance float64 `json:"distance"` // en km
+	Duration float64 `json:"duration"` // en segundos
+}
+
+// Path resultado de una búsqueda de camino más corto
+type Path struct {
+	Points   []models.Location `json:"points"`
+	Segments []Segment         `json:"segments"`
+	Distance float64           `json:"distance"` // en km
+	Duration float64           `json:"duration"` // en segundos
+	Cost     float64           `json:"cost"`
+}
+
+// Router calcula caminos más cortos sobre un grafo con A*
+type Router struct {
+	graph           *Graph
+	MaxSnapDistance float64
+	labelPool       sync.Pool // etiquetas reutilizables de oneToMany
+}
+
+// NewRouter crea un router para el grafo dado
+func NewRouter(graph *Graph) *Router {
+	graph.Build()
+	return &Router{
+		graph:           graph,
+		MaxSnapDistance: DefaultMaxSnapDistance,
+	}
+}
+
+// Graph retorna el grafo sobre el que trabaja el router
+func (r *Router) Graph() *Graph {
+	return r.graph
+}
+
+// FreeFlow retorna el peso por defecto: tiempo de viaje en auto a la velocidad de flujo libre
+func (r *Router) FreeFlow() Weighting {
+	profile, _ := ProfileByName(DefaultProfile)
+	return r.Weighting(profile, nil)
+}
+
+// travelTime retorna los segundos necesarios para recorrer length km a speed km/h
+func travelTime(length, speed float64) float64 {
+	if speed <= 0 {
+		return math.Inf(1)
+	}
+	return length / speed * 3600
+}
+
+// Route calcula la ruta más rápida entre dos ubicaciones a velocidad de flujo libre
+func (r *Router) Route(from, to models.Location) (*Path, error) {
+	return r.RouteWith(from, to, r.FreeFlow(), nil)
+}
+
+// RouteWith calcula la ruta de menor costo según cost. Si duration es nil
+// las duraciones reportadas son el mismo costo
+func (r *Router) RouteWith(from, to models.Location, cost, duration Weighting) (*Path, error) {
+	start, end, err := r.snapPair(from, to, cost)
+	if err != nil {
+		return nil, err
+	}
+	return r.shortestPath(start, end, cost, duration)
+}
+
+// snapPair ubica origen y destino sobre los tramos transitables según cost
+func (r *Router) snapPair(from, to models.Location, cost Weighting) ([]Snap, []Snap, error) {
+	if r.graph.Empty() {
+		return nil, nil, ErrNoGraph
+	}
+
+	allowed := passable(cost)
+	start := r.graph.Snap(from.Lat, from.Lng, r.MaxSnapDistance, allowed)
+	end := r.graph.Snap(to.Lat, to.Lng, r.MaxSnapDistance, allowed)
+	if start == nil || end == nil {
+		return nil, nil, ErrNoSnap
+	}
+	return start, end, nil
+}
+
+// passable retorna un filtro de tramos con costo finito
+func passable(cost Weighting) func(edge int) bool {
+	return func(edge int) bool {
+		return !math.IsInf(cost(edge, 0), 1)
+	}
+}
+
+// shortestPath ejecuta A* entre proyecciones de origen y destino
+func (r *Router) shortestPath(start, end []Snap, cost, duration Weighting) (*Path, error) {
+	g := r.graph
+	n := len(g.Nodes)
+	goal := n // nodo virtual de llegada
+
+	dist := make([]float64, n+1)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	prev := make([]int, n+1)
+	for i := range prev {
+		prev[i] = -1
+	}
+	seed := make(map[int]int) // nodo -> índice de la proyección de origen
+	var goalFrom, directStart, directEnd *Snap
+	target := end[0] // referencia para la heurística
+	maxSpeed := g.MaxSpeed()
+
+	heuristic := func(node int) float64 {
+		if node == goal || maxSpeed <= 0 {
+			return 0
+		}
+		nd := g.Nodes[node]
+		return travelTime(utils.HaversineDistance(nd.Lat, nd.Lng, target.Lat, target.Lng), maxSpeed)
+	}
+
+	pq := &nodeQueue{}
+	push := func(node int, d float64) {
+		dist[node] = d
+		heap.Push(pq, nodeItem{node: node, dist: d, priority: d + heuristic(node)})
+	}
+
+	for si := range start {
+		s := &start[si]
+		e := &g.Edges[s.Edge]
+		if d := (1 - s.Fraction) * cost(s.Edge, 0); d < dist[e.To] {
+			push(e.To, d)
+			seed[e.To] = si
+		}
+
+		// Origen y destino sobre el mismo tramo
+		for ei := range end {
+			t := &end[ei]
+			if t.Edge != s.Edge || t.Fraction < s.Fraction {
+				continue
+			}
+			if d := (t.Fraction - s.Fraction) * cost(s.Edge, 0); d < dist[goal] {
+				push(goal, d)
+				directStart, directEnd = s, t
+			}
+		}
+	}
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(nodeItem)
+		u := item.node
+		if item.dist > dist[u] {
+			continue
+		}
+		if u == goal {
+			break
+		}
+
+		for _, edgeIdx := range g.out[u] {
+			w := cost(edgeIdx, dist[u])
+			if math.IsInf(w, 1) {
+				continue
+			}
+			v := g.Edges[edgeIdx].To
+			if d := dist[u] + w; d < dist[v] {
+				push(v, d)
+				prev[v] = edgeIdx
+				delete(seed, v)
+			}
+		}
+
+		for ei := range end {
+			t := &end[ei]
+			if g.Edges[t.Edge].From != u {
+				continue
+			}
+			w := cost(t.Edge, dist[u])
+			if math.IsInf(w, 1) {
+				continue
+			}
+			if d := dist[u] + t.Fraction*w; d < dist[goal] {
+				push(goal, d)
+				prev[goal] = u
+				goalFrom, directStart, directEnd = t, nil, nil
+			}
+		}
+	}
+
+	if math.IsInf(dist[goal], 1) {
+		return nil, ErrNoRoute
+	}
+
+	if duration == nil {
+		duration = cost
+	}
+
+	if directStart != nil {
+		segments := []Segment{{
+			Edge:     directStart.Edge,
+			Distance: (directEnd.Fraction - directStart.Fraction) * g.Edges[directStart.Edge].Length,
+		}}
+		return r.buildPath(segments, *directStart, *directEnd, duration, dist[goal]), nil
+	}
+
+	// Reconstruir los tramos completos desde el destino hacia el origen
+	var full []int
+	node := prev[goal]
+	for {
+		if si, ok := seed[node]; ok {
+			s := start[si]
+			segments := []Segment{{Edge: s.Edge, Distance: (1 - s.Fraction) * g.Edges[s.Edge].Length}}
+			for i := len(full) - 1; i >= 0; i-- {
+				segments = append(segments, Segment{Edge: full[i], Distance: g.Edges[full[i]].Length})
+			}
+			segments = append(segments, Segment{Edge: goalFrom.Edge, Distance: goalFrom.Fraction * g.Edges[goalFrom.Edge].Length})
+			return r.buildPath(segments, s, *goalFrom, duration, dist[goal]), nil
+		}
+
+		edgeIdx := prev[node]
+		if edgeIdx < 0 {
+			return nil, ErrNoRoute
+		}
+		full = append(full, edgeIdx)
+		node = g.Edges[edgeIdx].From
+	}
+}
+
+// buildPath arma la geometría, distancias y duraciones de un camino
+func (r *Router) buildPath(segments []Segment, start, end Snap, duration Weighting, cost float64) *Path {
+	g := r.graph
+	path := &Path{
+		Points: []models.Location{{Lat: start.Lat, Lng: start.Lng}},
+		Cost:   cost,
+	}
+
+	for i, seg := range segments {
+		e := &g.Edges[seg.Edge]
+		if e.Length > 0 {
+			seg.Duration = seg.Distance / e.Length * duration(seg.Edge, path.Duration)
+		}
+		if seg.Distance <= 0 && len(segments) > 1 {
+			continue
+		}
+
+		if i == len(segments)-1 {
+			path.Points = append(path.Points, models.Location{Lat: end.Lat, Lng: end.Lng})
+		} else {
+			to := g.Nodes[e.To]
+			path.Points = append(path.Points, models.Location{Lat: to.Lat, Lng: to.Lng})
+		}
+
+		path.Segments = append(path.Segments, seg)
+		path.Distance += seg.Distance
+		path.Duration += seg.Duration
+	}
+
+	return path
+}
+
+// nodeItem elemento de la cola de prioridad
+type nodeItem struct {
+	node     int
+	dist     float64
+	priority float64
+}
+
+// nodeQueue cola de prioridad mínima para A*
+type nodeQueue []nodeItem
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(nodeItem)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
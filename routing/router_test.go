@@ -0,0 +1,193 @@
+package routing
+
+import (
+	"errors"
+	"gowaze/models"
+	"gowaze/utils"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// kmPerDeg km por grado sobre el ecuador, según la misma fórmula haversine del grafo
+var kmPerDeg = utils.HaversineDistance(0, 0, 0, 1)
+
+// ladderGraph red en forma de escalera sobre el ecuador:
+//
+//	A ── Norte (80) ── B ── Norte (80) ── C      lat 0
+//	|                  |                  |
+//	Oeste (50)     Centro (50)        Este (50)
+//	|                  |                  |
+//	D ── Sur (75) ──── E ── Sur (75) ──── F      lat -0.002
+//
+// Las columnas están en lng 0, 0.01 y 0.02. Además hay un tramo aislado al este de C
+// que no conecta con la escalera
+func ladderGraph() *Graph {
+	g := NewGraph()
+	nodes := map[string]int{
+		"A": g.AddNode(1, 0, 0),
+		"B": g.AddNode(2, 0, 0.01),
+		"C": g.AddNode(3, 0, 0.02),
+		"D": g.AddNode(4, -0.002, 0),
+		"E": g.AddNode(5, -0.002, 0.01),
+		"F": g.AddNode(6, -0.002, 0.02),
+		"X": g.AddNode(7, 0, 0.026),
+		"Y": g.AddNode(8, 0, 0.03),
+	}
+	roads := []struct {
+		from, to string
+		name     string
+		class    RoadClass
+		speed    float64
+	}{
+		{"A", "B", "Norte", "primary", 80},
+		{"B", "C", "Norte", "primary", 80},
+		{"D", "E", "Sur", "secondary", 75},
+		{"E", "F", "Sur", "secondary", 75},
+		{"A", "D", "Oeste", "residential", 50},
+		{"B", "E", "Centro", "residential", 50},
+		{"C", "F", "Este", "residential", 50},
+		{"X", "Y", "Aislada", "residential", 50},
+	}
+	for i, road := range roads {
+		for _, dir := range [][2]string{{road.from, road.to}, {road.to, road.from}} {
+			g.AddEdge(Edge{
+				From:   nodes[dir[0]],
+				To:     nodes[dir[1]],
+				Speed:  road.speed,
+				Name:   road.name,
+				Class:  road.class,
+				WayID:  int64(100 + i),
+				Access: accessAll,
+			})
+		}
+	}
+	return g
+}
+
+// roadNames nombres de las vías recorridas por un camino, sin repetir vías consecutivas
+// ni contar los tramos de largo nulo que resultan de ubicar un punto sobre un nodo
+func roadNames(g *Graph, path *Path) []string {
+	var names []string
+	for _, seg := range path.Segments {
+		if seg.Distance < 1e-9 {
+			continue
+		}
+		name := g.Edges[seg.Edge].Name
+		if len(names) == 0 || names[len(names)-1] != name {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// near indica si dos ubicaciones coinciden salvo errores de redondeo
+func near(a, b models.Location) bool {
+	return math.Abs(a.Lat-b.Lat) < 1e-9 && math.Abs(a.Lng-b.Lng) < 1e-9
+}
+
+// seconds tiempo en recorrer deg grados a speed km/h
+func seconds(deg, speed float64) float64 {
+	return deg * kmPerDeg / speed * 3600
+}
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		name         string
+		from, to     models.Location
+		wantNames    []string
+		wantDistance float64 // en grados sobre el ecuador
+		wantDuration float64
+		wantErr      error
+	}{
+		{
+			name:         "por la vía rápida",
+			from:         models.Location{Lat: 0, Lng: 0.001},
+			to:           models.Location{Lat: 0, Lng: 0.019},
+			wantNames:    []string{"Norte"},
+			wantDistance: 0.018,
+			wantDuration: seconds(0.018, 80),
+		},
+		{
+			name:         "dentro del mismo tramo",
+			from:         models.Location{Lat: 0, Lng: 0.002},
+			to:           models.Location{Lat: 0, Lng: 0.008},
+			wantNames:    []string{"Norte"},
+			wantDistance: 0.006,
+			wantDuration: seconds(0.006, 80),
+		},
+		{
+			name:         "en sentido contrario",
+			from:         models.Location{Lat: 0, Lng: 0.019},
+			to:           models.Location{Lat: 0, Lng: 0.001},
+			wantNames:    []string{"Norte"},
+			wantDistance: 0.018,
+			wantDuration: seconds(0.018, 80),
+		},
+		{
+			name:         "por la vía inferior",
+			from:         models.Location{Lat: -0.002, Lng: 0.001},
+			to:           models.Location{Lat: -0.002, Lng: 0.019},
+			wantNames:    []string{"Sur"},
+			wantDistance: 0.018,
+			wantDuration: seconds(0.018, 75),
+		},
+		{
+			name:         "cambio de vía por el tramo central",
+			from:         models.Location{Lat: 0, Lng: 0.001},
+			to:           models.Location{Lat: -0.002, Lng: 0.019},
+			wantNames:    []string{"Norte", "Centro", "Sur"},
+			wantDistance: 0.02,
+			wantDuration: seconds(0.009, 80) + seconds(0.002, 50) + seconds(0.009, 75),
+		},
+		{
+			name:    "punto fuera de la red",
+			from:    models.Location{Lat: 0, Lng: 0.001},
+			to:      models.Location{Lat: 1, Lng: 1},
+			wantErr: ErrNoSnap,
+		},
+		{
+			name:    "componente aislada",
+			from:    models.Location{Lat: 0, Lng: 0.001},
+			to:      models.Location{Lat: 0, Lng: 0.028},
+			wantErr: ErrNoRoute,
+		},
+	}
+
+	g := ladderGraph()
+	router := NewRouter(g)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := router.Route(tt.from, tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Route retornó %v, esperado %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if got := roadNames(g, path); !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("vías = %v, esperado %v", got, tt.wantNames)
+			}
+			if want := tt.wantDistance * kmPerDeg; math.Abs(path.Distance-want) > 1e-3 {
+				t.Errorf("distancia = %.4f km, esperado %.4f", path.Distance, want)
+			}
+			if math.Abs(path.Duration-tt.wantDuration) > 0.1 {
+				t.Errorf("duración = %.2f s, esperado %.2f", path.Duration, tt.wantDuration)
+			}
+			first, last := path.Points[0], path.Points[len(path.Points)-1]
+			if !near(first, tt.from) || !near(last, tt.to) {
+				t.Errorf("extremos = %v → %v, esperado %v → %v", first, last, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestRouteEmptyGraph(t *testing.T) {
+	router := NewRouter(NewGraph())
+	if _, err := router.Route(models.Location{}, models.Location{Lat: 0, Lng: 0.01}); !errors.Is(err, ErrNoGraph) {
+		t.Errorf("Route retornó %v, esperado %v", err, ErrNoGraph)
+	}
+}
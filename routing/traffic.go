@@ -0,0 +1,63 @@
+package routing
+
+import "math"
+
+// Traffic condiciones de tráfico en vivo asociadas a tramos de la red
+type Traffic struct {
+	speeds    map[int]float64 // velocidad observada en km/h
+	distances map[int]float64 // distancia del tramo a la observación que fijó su velocidad
+	factors   map[int]float64 // factor multiplicativo de velocidad por incidentes (0-1]
+}
+
+// NewTraffic crea un conjunto vacío de condiciones de tráfico
+func NewTraffic() *Traffic {
+	return &Traffic{
+		speeds:    make(map[int]float64),
+		distances: make(map[int]float64),
+		factors:   make(map[int]float64),
+	}
+}
+
+// SetSpeed asigna una velocidad observada a un tramo. Si varias observaciones
+// cubren el mismo tramo se conserva la más cercana
+func (t *Traffic) SetSpeed(edge int, speed, distance float64) {
+	if d, ok := t.distances[edge]; ok && d <= distance {
+		return
+	}
+	t.speeds[edge] = speed
+	t.distances[edge] = distance
+}
+
+// ApplyFactor reduce la velocidad de un tramo por un incidente. Los factores se acumulan
+func (t *Traffic) ApplyFactor(edge int, factor float64) {
+	if f, ok := t.factors[edge]; ok {
+		factor *= f
+	}
+	t.factors[edge] = factor
+}
+
+// Len retorna la cantidad de tramos afectados
+func (t *Traffic) Len() int {
+	affected := len(t.speeds)
+	for edge := range t.factors {
+		if _, ok := t.speeds[edge]; !ok {
+			affected++
+		}
+	}
+	return affected
+}
+
+// Apply retorna la velocidad efectiva de un tramo a partir de su velocidad de flujo libre.
+// Las observaciones nunca superan esa velocidad para mantener la heurística admisible
+func (t *Traffic) Apply(edge int, speed float64) float64 {
+	if t == nil {
+		return speed
+	}
+	if observed, ok := t.speeds[edge]; ok {
+		speed = math.Min(speed, observed)
+	}
+	if factor, ok := t.factors[edge]; ok {
+		speed *= factor
+	}
+	return speed
+}
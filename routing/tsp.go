@@ -0,0 +1,106 @@
+package routing
+
+import "math"
+
+// exactOrderLimit cantidad máxima de paradas intermedias para búsqueda exhaustiva
+const exactOrderLimit = 7
+
+// OptimizeOrder calcula el orden de visita de menor costo total para una matriz
+// de costos (posiblemente asimétrica). La primera y la última parada quedan fijas.
+// Con pocas paradas se prueban todas las permutaciones; con más se usa vecino más
+// cercano seguido de mejoras 2-opt
+func OptimizeOrder(costs [][]float64) []int {
+	n := len(costs)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if n <= 3 {
+		return order
+	}
+
+	if n-2 <= exactOrderLimit {
+		best := append([]int(nil), order...)
+		bestCost := tourCost(costs, order)
+		permute(order, 1, n-1, func(candidate []int) {
+			if c := tourCost(costs, candidate); c < bestCost {
+				bestCost = c
+				copy(best, candidate)
+			}
+		})
+		return best
+	}
+
+	order = nearestNeighbor(costs)
+	improveTwoOpt(costs, order)
+	return order
+}
+
+// tourCost costo total de recorrer las paradas en el orden dado
+func tourCost(costs [][]float64, order []int) float64 {
+	total := 0.0
+	for i := 0; i+1 < len(order); i++ {
+		total += costs[order[i]][order[i+1]]
+	}
+	return total
+}
+
+// permute genera todas las permutaciones de order[lo:hi]
+func permute(order []int, lo, hi int, visit func([]int)) {
+	if lo >= hi-1 {
+		visit(order)
+		return
+	}
+	for i := lo; i < hi; i++ {
+		order[lo], order[i] = order[i], order[lo]
+		permute(order, lo+1, hi, visit)
+		order[lo], order[i] = order[i], order[lo]
+	}
+}
+
+// nearestNeighbor construye un recorrido eligiendo siempre la parada más cercana
+func nearestNeighbor(costs [][]float64) []int {
+	n := len(costs)
+	visited := make([]bool, n)
+	order := []int{0}
+	visited[0], visited[n-1] = true, true
+
+	current := 0
+	for len(order) < n-1 {
+		next, nextCost := -1, math.Inf(1)
+		for j := 1; j < n-1; j++ {
+			if !visited[j] && (next < 0 || costs[current][j] < nextCost) {
+				next, nextCost = j, costs[current][j]
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		current = next
+	}
+	return append(order, n-1)
+}
+
+// improveTwoOpt invierte subsecuencias intermedias mientras el costo total disminuya
+func improveTwoOpt(costs [][]float64, order []int) {
+	bestCost := tourCost(costs, order)
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < len(order)-2; i++ {
+			for j := i + 1; j < len(order)-1; j++ {
+				reverse(order, i, j)
+				if c := tourCost(costs, order); c < bestCost-1e-9 {
+					bestCost = c
+					improved = true
+				} else {
+					reverse(order, i, j)
+				}
+			}
+		}
+	}
+}
+
+func reverse(order []int, i, j int) {
+	for ; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+}
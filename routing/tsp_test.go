@@ -0,0 +1,126 @@
+package routing
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// euclideanCosts matriz de distancias entre puntos del plano
+func euclideanCosts(points [][2]float64) [][]float64 {
+	costs := make([][]float64, len(points))
+	for i, p := range points {
+		costs[i] = make([]float64, len(points))
+		for j, q := range points {
+			costs[i][j] = math.Hypot(p[0]-q[0], p[1]-q[1])
+		}
+	}
+	return costs
+}
+
+// randomPoints n puntos al azar en el cuadrado unitario, reproducibles por semilla
+func randomPoints(n int, seed int64) [][2]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([][2]float64, n)
+	for i := range points {
+		points[i] = [2]float64{rng.Float64(), rng.Float64()}
+	}
+	return points
+}
+
+// circlePoints n puntos sobre un semicírculo, con las paradas intermedias desordenadas.
+// El recorrido óptimo entre el primero y el último sigue el arco
+func circlePoints(n int) [][2]float64 {
+	points := make([][2]float64, n)
+	for i := range points {
+		k := i
+		if i > 0 && i < n-1 {
+			// 7 es coprimo con n-2 en los tamaños usados, así que cada posición aparece una vez
+			k = 1 + (i-1)*7%(n-2)
+		}
+		angle := math.Pi * float64(k) / float64(n-1)
+		points[i] = [2]float64{math.Cos(angle), math.Sin(angle)}
+	}
+	return points
+}
+
+// asymmetricCosts matriz en la que ir hacia índices mayores es barato y volver es caro
+func asymmetricCosts(n int, seed int64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	costs := make([][]float64, n)
+	for i := range costs {
+		costs[i] = make([]float64, n)
+		for j := range costs[i] {
+			if j > i {
+				costs[i][j] = float64(j-i) + rng.Float64()
+			} else if j < i {
+				costs[i][j] = 10*float64(i-j) + rng.Float64()
+			}
+		}
+	}
+	return costs
+}
+
+// bruteForceCost costo del mejor recorrido probando todas las permutaciones
+func bruteForceCost(costs [][]float64) float64 {
+	order := make([]int, len(costs))
+	for i := range order {
+		order[i] = i
+	}
+	best := math.Inf(1)
+	permute(order, 1, len(order)-1, func(candidate []int) {
+		best = math.Min(best, tourCost(costs, candidate))
+	})
+	return best
+}
+
+func TestOptimizeOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		costs     [][]float64
+		tolerance float64 // sobrecosto relativo aceptado sobre el óptimo
+	}{
+		{"exhaustiva con 5 paradas", euclideanCosts(randomPoints(5, 1)), 0},
+		{"exhaustiva con 9 paradas", euclideanCosts(randomPoints(9, 2)), 0},
+		{"exhaustiva asimétrica", asymmetricCosts(8, 3), 0},
+		{"2-opt sobre un semicírculo", euclideanCosts(circlePoints(11)), 0},
+		{"2-opt con 10 paradas", euclideanCosts(randomPoints(10, 4)), 0.1},
+		{"2-opt con 11 paradas", euclideanCosts(randomPoints(11, 5)), 0.1},
+		{"2-opt asimétrica", asymmetricCosts(10, 6), 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := len(tt.costs)
+			order := OptimizeOrder(tt.costs)
+
+			if len(order) != n || order[0] != 0 || order[n-1] != n-1 {
+				t.Fatalf("orden %v no conserva los extremos", order)
+			}
+			seen := make([]bool, n)
+			for _, stop := range order {
+				if stop < 0 || stop >= n || seen[stop] {
+					t.Fatalf("orden %v no es una permutación", order)
+				}
+				seen[stop] = true
+			}
+
+			got, best := tourCost(tt.costs, order), bruteForceCost(tt.costs)
+			if got > best*(1+tt.tolerance)+1e-9 {
+				t.Errorf("costo %.4f, óptimo %.4f (tolerancia %.0f%%)", got, best, tt.tolerance*100)
+			}
+		})
+	}
+}
+
+func TestOptimizeOrderFewStops(t *testing.T) {
+	for n := 0; n <= 3; n++ {
+		order := OptimizeOrder(euclideanCosts(randomPoints(n, int64(n))))
+		for i, stop := range order {
+			if stop != i {
+				t.Errorf("con %d paradas el orden %v no es el original", n, order)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrBlobNotFound indica que no hay un archivo con esa clave
+var ErrBlobNotFound = errors.New("archivo no encontrado")
+
+// blobKeyPattern claves aceptadas: nombres simples, sin rutas
+var blobKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9]+)?$`)
+
+// BlobInfo clave y fecha de escritura de un archivo guardado
+type BlobInfo struct {
+	Key       string
+	UpdatedAt time.Time
+}
+
+// BlobStore guarda archivos binarios, como las fotos de los reportes, por clave.
+// MemoryBlobStore los mantiene en memoria y DiskBlobStore en un directorio; otros
+// servicios (S3, GCS) pueden implementar la misma interfaz
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	List() ([]BlobInfo, error)
+}
+
+func validBlobKey(key string) error {
+	if !blobKeyPattern.MatchString(key) {
+		return fmt.Errorf("clave de archivo inválida %q", key)
+	}
+	return nil
+}
+
+// MemoryBlobStore guarda los archivos en memoria
+type MemoryBlobStore struct {
+	blobs map[string][]byte
+	times map[string]time.Time
+	mu    sync.RWMutex
+}
+
+// NewMemoryBlobStore crea un almacén de archivos en memoria vacío
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{
+		blobs: make(map[string][]byte),
+		times: make(map[string]time.Time),
+	}
+}
+
+// Put guarda o reemplaza un archivo
+func (s *MemoryBlobStore) Put(key string, data []byte) error {
+	if err := validBlobKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = append([]byte(nil), data...)
+	s.times[key] = time.Now()
+	return nil
+}
+
+// Get obtiene un archivo
+func (s *MemoryBlobStore) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return data, nil
+}
+
+// Delete elimina un archivo; no falla si no existe
+func (s *MemoryBlobStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	delete(s.times, key)
+	return nil
+}
+
+// List retorna los archivos guardados
+func (s *MemoryBlobStore) List() ([]BlobInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	blobs := make([]BlobInfo, 0, len(s.blobs))
+	for key := range s.blobs {
+		blobs = append(blobs, BlobInfo{Key: key, UpdatedAt: s.times[key]})
+	}
+	return blobs, nil
+}
+
+// DiskBlobStore guarda cada archivo en un directorio local con su clave como nombre
+type DiskBlobStore struct {
+	dir string
+}
+
+// NewDiskBlobStore crea un almacén de archivos en el directorio dir, creándolo si no existe
+func NewDiskBlobStore(dir string) (*DiskBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskBlobStore{dir: dir}, nil
+}
+
+// Put guarda o reemplaza un archivo. Se escribe en un temporal y se renombra para
+// que nunca se lea a medias
+func (s *DiskBlobStore) Put(key string, data []byte) error {
+	if err := validBlobKey(key); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get obtiene un archivo
+func (s *DiskBlobStore) Get(key string) ([]byte, error) {
+	if err := validBlobKey(key); err != nil {
+		return nil, ErrBlobNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+// Delete elimina un archivo; no falla si no existe
+func (s *DiskBlobStore) Delete(key string) error {
+	if err := validBlobKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// List retorna los archivos guardados, sin los temporales de escrituras en curso
+func (s *DiskBlobStore) List() ([]BlobInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]BlobInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !blobKeyPattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		blobs = append(blobs, BlobInfo{Key: entry.Name(), UpdatedAt: info.ModTime()})
+	}
+	return blobs, nil
+}
+
+// OpenBlobStore crea el almacén de archivos indicado al iniciar: "memory" o "disk"
+// (en el directorio dir)
+func OpenBlobStore(kind, dir string) (BlobStore, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBlobStore(), nil
+	case "disk":
+		return NewDiskBlobStore(dir)
+	}
+	return nil, fmt.Errorf("almacén de archivos desconocido %q (memory o disk)", kind)
+}
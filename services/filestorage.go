@@ -0,0 +1,392 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gowaze/models"
+	"gowaze/routing"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// walFile nombre del registro de escritura anticipada dentro del directorio de datos
+	walFile = "wal.log"
+	// snapshotFile nombre de la instantánea compactada dentro del directorio de datos
+	snapshotFile = "snapshot.json"
+	// speedsFile nombre del historial de velocidades dentro del directorio de datos
+	speedsFile = "speeds.json"
+	// snapshotInterval frecuencia de las instantáneas periódicas
+	snapshotInterval = 10 * time.Minute
+	// maxWALRecords registros del WAL a partir de los cuales se compacta sin esperar al intervalo
+	maxWALRecords = 10000
+)
+
+// storageSnapshot estado completo del almacenamiento en un instante
+type storageSnapshot struct {
+	TakenAt      time.Time                      `json:"taken_at"`
+	Users        map[int]*models.User           `json:"users"`
+	Reports      map[int]*models.Report         `json:"reports"`
+	TrafficData  map[string]*models.TrafficData `json:"traffic"`
+	Trips        map[int]*models.Trip           `json:"trips"`
+	Voters       map[int]map[int]int            `json:"voters"`
+	NextUserID   int                            `json:"next_user_id"`
+	NextReportID int                            `json:"next_report_id"`
+	NextTripID   int                            `json:"next_trip_id"`
+}
+
+// errStorageClosed indica una modificación posterior a Close
+var errStorageClosed = errors.New("almacenamiento cerrado")
+
+// FileStorage almacenamiento en memoria respaldado en disco. Cada modificación se
+// escribe en un registro de escritura anticipada (WAL) y solo se aplica en memoria
+// una vez sincronizada con el disco; si la escritura falla, el error llega a quien
+// la pidió y el estado no cambia. El estado se compacta periódicamente en una
+// instantánea, tras la cual el WAL se reinicia. Al abrirlo se carga la instantánea
+// y se reaplica el WAL
+type FileStorage struct {
+	*MemoryStorage
+	dir     string
+	wal     *os.File
+	writer  *bufio.Writer
+	size    int64 // bytes del WAL que terminan en un registro completo
+	records int   // registros en el WAL desde la última instantánea
+	broken  error // el WAL no se pudo devolver a un estado válido tras un error
+	compact chan struct{}
+	done    chan struct{}
+	mu      sync.Mutex // protege el WAL
+}
+
+// NewFileStorage abre o crea el directorio de datos dir y recupera su contenido
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	fs := &FileStorage{
+		MemoryStorage: NewMemoryStorage(),
+		dir:           dir,
+		compact:       make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+	if err := fs.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	records, size, err := fs.replay()
+	if err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// Descartar un registro incompleto al final para que el próximo no quede pegado a él
+	if err := wal.Truncate(size); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	fs.wal = wal
+	fs.writer = bufio.NewWriter(wal)
+	fs.size = size
+	fs.journal = fs.append
+
+	users, reports, trafficPoints := fs.GetStats()
+	log.Printf("💾 Datos recuperados de %s: %d registros del WAL (users: %d, reports: %d, traffic: %d)",
+		dir, records, users, reports, trafficPoints)
+
+	// Compactar lo recuperado para empezar con un WAL vacío
+	if records > 0 {
+		if err := fs.Snapshot(); err != nil {
+			log.Printf("Error guardando instantánea: %v", err)
+		}
+	}
+	go fs.startSnapshots()
+	return fs, nil
+}
+
+// loadSnapshot carga la última instantánea, si existe
+func (fs *FileStorage) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(fs.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot storageSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("instantánea inválida: %w", err)
+	}
+
+	s := fs.MemoryStorage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range snapshot.Users {
+		s.putUser(user)
+	}
+	for _, report := range snapshot.Reports {
+		s.putReport(report)
+	}
+	for key, traffic := range snapshot.TrafficData {
+		s.putTraffic(key, traffic)
+	}
+	for _, trip := range snapshot.Trips {
+		s.putTrip(trip)
+	}
+	for reportID, voters := range snapshot.Voters {
+		for userID, vote := range voters {
+			s.putVote(reportID, userID, vote)
+		}
+	}
+
+	if snapshot.NextUserID > s.NextUserID {
+		s.NextUserID = snapshot.NextUserID
+	}
+	if snapshot.NextReportID > s.NextReportID {
+		s.NextReportID = snapshot.NextReportID
+	}
+	if snapshot.NextTripID > s.NextTripID {
+		s.NextTripID = snapshot.NextTripID
+	}
+	return nil
+}
+
+// replay reaplica las modificaciones del WAL y retorna cuántas aplicó y hasta qué
+// byte llegan los registros completos. Una última línea incompleta, por ejemplo
+// tras una caída durante la escritura, se descarta
+func (fs *FileStorage) replay() (int, int64, error) {
+	path := filepath.Join(fs.dir, walFile)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	s := fs.MemoryStorage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reader := bufio.NewReader(file)
+	records, line := 0, 0
+	var size int64
+	for {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				log.Printf("⚠️  Registro incompleto al final de %s descartado", path)
+			}
+			return records, size, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		size += int64(len(data))
+		line++
+
+		var record storageRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Printf("⚠️  Registro inválido en %s:%d: %v", path, line, err)
+			continue
+		}
+		s.apply(record)
+		records++
+	}
+}
+
+// append escribe una modificación al final del WAL y la sincroniza con el disco. Si
+// falla, recorta lo que haya llegado a escribirse y reinicia el buffer, cuyo error
+// persistiría en las escrituras siguientes, de modo que el WAL siga terminando en el
+// último registro completo
+func (fs *FileStorage) append(record storageRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("serializando registro %s: %w", record.Op, err)
+	}
+	data = append(data, '\n')
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.wal == nil {
+		return errStorageClosed
+	}
+	if fs.broken != nil {
+		return fmt.Errorf("WAL inutilizable hasta la próxima instantánea: %w", fs.broken)
+	}
+
+	if _, err = fs.writer.Write(data); err == nil {
+		if err = fs.writer.Flush(); err == nil {
+			err = fs.wal.Sync()
+		}
+	}
+	if err != nil {
+		fs.writer.Reset(fs.wal)
+		if truncErr := fs.wal.Truncate(fs.size); truncErr != nil {
+			fs.broken = truncErr
+		}
+		return fmt.Errorf("escribiendo en el WAL: %w", err)
+	}
+
+	fs.size += int64(len(data))
+	fs.records++
+	if fs.records >= maxWALRecords {
+		// La modificación en curso retiene el almacenamiento: compactar después
+		select {
+		case fs.compact <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Snapshot descarta los datos vencidos, escribe la instantánea en un archivo temporal,
+// lo reemplaza atómicamente y recién entonces vacía el WAL. Si el proceso cae entre
+// ambos pasos, reaplicar el WAL sobre la nueva instantánea produce el mismo estado
+func (fs *FileStorage) Snapshot() error {
+	fs.cleanup()
+
+	// Las modificaciones esperan a que termine: lo que escribieran en el WAL se perdería
+	// al vaciarlo
+	s := fs.MemoryStorage
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.wal == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(storageSnapshot{
+		TakenAt:      time.Now(),
+		Users:        s.Users,
+		Reports:      s.Reports,
+		TrafficData:  s.TrafficData,
+		Trips:        s.Trips,
+		Voters:       s.Voters,
+		NextUserID:   s.NextUserID,
+		NextReportID: s.NextReportID,
+		NextTripID:   s.NextTripID,
+	})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(fs.dir, snapshotFile)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	if err := fs.wal.Truncate(0); err != nil {
+		return err
+	}
+	fs.writer.Reset(fs.wal)
+	fs.size = 0
+	fs.records = 0
+	fs.broken = nil
+	return fs.wal.Sync()
+}
+
+// SaveSpeedHistory guarda el historial de velocidades en su propio archivo, fuera del
+// WAL por su tamaño. Se reemplaza atómicamente para no dejarlo a medias
+func (fs *FileStorage) SaveSpeedHistory(data *routing.HistoryData) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(fs.dir, speedsFile)
+	if err := writeFileSync(path+".tmp", encoded); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// LoadSpeedHistory obtiene el historial de velocidades guardado, nil si no hay
+func (fs *FileStorage) LoadSpeedHistory() (*routing.HistoryData, error) {
+	encoded, err := os.ReadFile(filepath.Join(fs.dir, speedsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data routing.HistoryData
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, fmt.Errorf("historial de velocidades inválido: %w", err)
+	}
+	return &data, nil
+}
+
+// writeFileSync escribe un archivo completo y lo sincroniza con el disco
+func writeFileSync(path string, data []byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// startSnapshots guarda instantáneas periódicas, o cuando el WAL crece demasiado,
+// hasta que se cierre el almacenamiento
+func (fs *FileStorage) startSnapshots() {
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-fs.compact:
+		case <-fs.done:
+			return
+		}
+		if err := fs.Snapshot(); err != nil {
+			log.Printf("Error guardando instantánea: %v", err)
+		}
+	}
+}
+
+// Close guarda una última instantánea y cierra el WAL
+func (fs *FileStorage) Close() error {
+	err := fs.Snapshot()
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.wal == nil {
+		return nil
+	}
+	close(fs.done)
+	if closeErr := fs.wal.Close(); err == nil {
+		err = closeErr
+	}
+	fs.wal = nil
+	return err
+}
+
+// OpenStorage crea el almacenamiento indicado al iniciar: "memory" o "file" (en el directorio dir)
+func OpenStorage(kind, dir string) (Storage, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryStorage(), nil
+	case "file":
+		return NewFileStorage(dir)
+	}
+	return nil, fmt.Errorf("almacenamiento desconocido %q (memory o file)", kind)
+}
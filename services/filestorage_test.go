@@ -0,0 +1,257 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"gowaze/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Ubicación base de los reportes de prueba; 0.00045° de latitud son unos 50 m
+const (
+	testLat = 14.0818
+	testLng = -87.2068
+)
+
+// crash cierra el WAL sin guardar una instantánea, como si el proceso cayera
+func crash(fs *FileStorage) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	close(fs.done)
+	fs.wal.Close()
+	fs.wal = nil
+}
+
+// storageState estado completo del almacenamiento serializado, para comparar el
+// recuperado con el original sin depender de la representación interna de time.Time
+func storageState(t *testing.T, s *MemoryStorage) string {
+	t.Helper()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, err := json.Marshal(storageSnapshot{
+		Users:        s.Users,
+		Reports:      s.Reports,
+		TrafficData:  s.TrafficData,
+		Trips:        s.Trips,
+		Voters:       s.Voters,
+		NextUserID:   s.NextUserID,
+		NextReportID: s.NextReportID,
+		NextTripID:   s.NextTripID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// populate aplica una modificación de cada clase: un usuario, reportes con un
+// duplicado anónimo agrupado, votos, un reporte retirado, tráfico y un viaje
+func populate(t *testing.T, s Storage) {
+	t.Helper()
+	now := time.Now()
+	user, err := s.CreateUser("conductor", testLat, testLng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accident, _, err := s.CreateReport(&models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.CreateReport(&models.Report{Type: "accident", Lat: testLat, Lng: testLng, Description: "Choque"}); err != nil {
+		t.Fatal(err)
+	}
+	hazard, _, err := s.CreateReport(&models.Report{Type: "hazard", Lat: testLat + 0.01, Lng: testLng})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct{ reportID, userID, vote int }{
+		{accident.ID, 2, VoteUp},
+		{accident.ID, 3, VoteDown},
+		{hazard.ID, 2, VoteDown},
+		{hazard.ID, 3, VoteDown},
+		{hazard.ID, 4, VoteDown},
+	} {
+		if _, _, err := s.VoteReport(v.reportID, v.userID, v.vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.UpdateTrafficData("tramo-1", &models.TrafficData{Lat: testLat, Lng: testLng, Speed: 23, Congestion: "medium", Timestamp: now}); err != nil {
+		t.Fatal(err)
+	}
+	trip := &models.Trip{SessionID: "abc", Profile: "car", Distance: 4.2, Arrived: true, StartedAt: now.Add(-10 * time.Minute), EndedAt: now}
+	if _, err := s.SaveTrip(trip); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStorageRecovery(t *testing.T) {
+	tests := []struct {
+		name         string
+		stop         func(t *testing.T, fs *FileStorage)
+		wantSnapshot bool // la recuperación parte de una instantánea
+		wantWAL      bool // la recuperación reaplica registros del WAL
+	}{
+		{
+			name:    "reaplicando el WAL tras una caída",
+			stop:    func(t *testing.T, fs *FileStorage) { crash(fs) },
+			wantWAL: true,
+		},
+		{
+			name: "desde la instantánea al cerrar",
+			stop: func(t *testing.T, fs *FileStorage) {
+				if err := fs.Close(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantSnapshot: true,
+		},
+		{
+			name: "limpieza reaplicada tras una caída",
+			stop: func(t *testing.T, fs *FileStorage) {
+				// Envejecer en memoria todo lo que borra la limpieza
+				old := time.Now().Add(-8 * 24 * time.Hour)
+				for _, user := range fs.Users {
+					user.LastSeen = old
+				}
+				for _, report := range fs.Reports {
+					report.CreatedAt, report.ConfirmedAt = old, old
+				}
+				for _, trip := range fs.Trips {
+					trip.EndedAt = old
+				}
+				for _, traffic := range fs.TrafficData {
+					traffic.Timestamp = old
+				}
+				fs.cleanup()
+				if users, reports, traffic := fs.GetStats(); users+reports+traffic+len(fs.Trips) != 0 {
+					t.Fatalf("la limpieza dejó %d usuarios, %d reportes, %d datos de tráfico y %d viajes", users, reports, traffic, len(fs.Trips))
+				}
+				crash(fs)
+			},
+			wantWAL: true,
+		},
+		{
+			name: "instantánea y WAL posterior",
+			stop: func(t *testing.T, fs *FileStorage) {
+				if err := fs.Snapshot(); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := fs.CreateUser("tardío", testLat, testLng); err != nil {
+					t.Fatal(err)
+				}
+				crash(fs)
+			},
+			wantSnapshot: true,
+			wantWAL:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fs, err := NewFileStorage(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			populate(t, fs)
+			tt.stop(t, fs)
+			want := storageState(t, fs.MemoryStorage)
+
+			_, err = os.Stat(filepath.Join(dir, snapshotFile))
+			if hasSnapshot := err == nil; hasSnapshot != tt.wantSnapshot {
+				t.Errorf("instantánea guardada = %v, esperado %v", hasSnapshot, tt.wantSnapshot)
+			}
+			wal, err := os.ReadFile(filepath.Join(dir, walFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hasWAL := len(wal) > 0; hasWAL != tt.wantWAL {
+				t.Errorf("registros en el WAL = %v, esperado %v", hasWAL, tt.wantWAL)
+			}
+
+			recovered, err := NewFileStorage(dir)
+			if err != nil {
+				t.Fatalf("NewFileStorage: %v", err)
+			}
+			defer recovered.Close()
+			if got := storageState(t, recovered.MemoryStorage); got != want {
+				t.Errorf("estado recuperado:\n%s\nesperado:\n%s", got, want)
+			}
+
+			// Los IDs siguen desde donde quedaron, sin pisar los recuperados
+			report, _, err := recovered.CreateReport(&models.Report{Type: "police", Lat: testLat - 0.01, Lng: testLng})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.ID != fs.NextReportID {
+				t.Errorf("ID del siguiente reporte = %d, esperado %d", report.ID, fs.NextReportID)
+			}
+		})
+	}
+}
+
+func TestFileStorageTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	populate(t, fs)
+	crash(fs)
+	want := storageState(t, fs.MemoryStorage)
+
+	// Un registro a medias al final, como el que deja una caída durante la escritura
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.WriteString(`{"op":"user","user":{"id":9,"user`)
+	wal.Close()
+
+	recovered, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage: %v", err)
+	}
+	if got := storageState(t, recovered.MemoryStorage); got != want {
+		t.Errorf("estado recuperado:\n%s\nesperado:\n%s", got, want)
+	}
+
+	// El registro siguiente no queda pegado al descartado
+	if _, err := recovered.CreateUser("siguiente", testLat, testLng); err != nil {
+		t.Fatal(err)
+	}
+	crash(recovered)
+	want = storageState(t, recovered.MemoryStorage)
+
+	again, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage: %v", err)
+	}
+	defer again.Close()
+	if got := storageState(t, again.MemoryStorage); got != want {
+		t.Errorf("estado tras la segunda recuperación:\n%s\nesperado:\n%s", got, want)
+	}
+}
+
+func TestFileStorageClosed(t *testing.T) {
+	fs, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Errorf("cerrar dos veces retornó %v", err)
+	}
+
+	if _, err := fs.CreateUser("tarde", testLat, testLng); !errors.Is(err, errStorageClosed) {
+		t.Errorf("CreateUser tras Close retornó %v, esperado %v", err, errStorageClosed)
+	}
+	if users, _, _ := fs.GetStats(); users != 0 {
+		t.Errorf("la modificación rechazada se aplicó en memoria: %d usuarios", users)
+	}
+}
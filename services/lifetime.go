@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"gowaze/models"
+	"math"
+	"strings"
+	"time"
+)
+
+// reportMaxAge antigüedad máxima de un reporte, aunque siga recibiendo confirmaciones
+const reportMaxAge = 24 * time.Hour
+
+// DefaultReportLifetime vida de los reportes de tipos que no están en el registro
+const DefaultReportLifetime = 24 * time.Hour
+
+// ReportLifetime retorna la vida de un tipo y subtipo de reporte desde su última
+// confirmación: la del subtipo, si tiene una propia, o la del tipo
+func ReportLifetime(reportType, subtype string) time.Duration {
+	t, sub, ok := LookupReportType(reportType, subtype)
+	switch {
+	case !ok:
+		return DefaultReportLifetime
+	case sub != nil && sub.Lifetime != 0:
+		return sub.Lifetime
+	}
+	return t.Lifetime
+}
+
+// SetReportLifetimes reemplaza la vida de algunos tipos o subtipos de reporte a partir
+// de una lista "tipo=duración" o "tipo/subtipo=duración" separada por comas, p. ej.
+// "police=30m,hazard/pothole=12h". La vida de un tipo no cambia la de sus subtipos
+// con vida propia. Se usa al iniciar, antes de atender solicitudes
+func SetReportLifetimes(spec string) error {
+	type override struct {
+		reportType *ReportType
+		subtype    *ReportSubtype
+		lifetime   time.Duration
+	}
+	var overrides []override
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("vida inválida %q: use tipo=duración", item)
+		}
+		lifetime, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || lifetime <= 0 || lifetime > reportMaxAge {
+			return fmt.Errorf("vida inválida %q: duración entre 0 y %v", item, reportMaxAge)
+		}
+		names := strings.SplitN(strings.TrimSpace(parts[0]), "/", 2)
+		subtype := ""
+		if len(names) == 2 {
+			subtype = names[1]
+		}
+		t, sub, ok := LookupReportType(names[0], subtype)
+		if !ok {
+			return fmt.Errorf("vida inválida %q: %v", item, ErrInvalidReportType)
+		}
+		overrides = append(overrides, override{t, sub, lifetime})
+	}
+
+	for _, o := range overrides {
+		if o.subtype != nil {
+			o.subtype.Lifetime = o.lifetime
+		} else {
+			o.reportType.Lifetime = o.lifetime
+		}
+	}
+	return nil
+}
+
+// reportExpiresAt instante en que vence un reporte: su vida cuenta desde la última
+// confirmación, sin superar reportMaxAge desde su creación
+func reportExpiresAt(report *models.Report) time.Time {
+	confirmed := report.ConfirmedAt
+	if confirmed.IsZero() {
+		confirmed = report.CreatedAt
+	}
+	expires := confirmed.Add(ReportLifetime(report.Type, report.Subtype))
+	if limit := report.CreatedAt.Add(reportMaxAge); limit.Before(expires) {
+		return limit
+	}
+	return expires
+}
+
+// reportActive indica si un reporte sigue vigente en un instante
+func reportActive(report *models.Report, now time.Time) bool {
+	return now.Before(reportExpiresAt(report))
+}
+
+// reportConfidence estima la confianza (0-1) en que un reporte siga vigente: decae
+// linealmente desde la última confirmación hasta su vencimiento y se reduce con los
+// votos "ya no está" recibidos desde entonces
+func reportConfidence(report *models.Report, now time.Time) float64 {
+	confirmed := report.ConfirmedAt
+	if confirmed.IsZero() {
+		confirmed = report.CreatedAt
+	}
+	span := reportExpiresAt(report).Sub(confirmed)
+	if span <= 0 {
+		return 0
+	}
+	freshness := 1 - float64(now.Sub(confirmed))/float64(span)
+	freshness = math.Max(0, math.Min(1, freshness))
+
+	support := 1.0
+	if upvotes := math.Max(float64(report.Upvotes), 1); report.Dismissals > 0 {
+		support = upvotes / (upvotes + float64(report.Dismissals))
+	}
+	return math.Round(freshness*support*100) / 100
+}
+
+// presentReport copia un reporte con su vencimiento y confianza calculados al instante
+// now, sin modificar el almacenado
+func presentReport(report *models.Report, now time.Time) *models.Report {
+	presented := *report
+	presented.ExpiresAt = reportExpiresAt(report)
+	presented.Confidence = reportConfidence(report, now)
+	return &presented
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gowaze/models"
+	"gowaze/utils"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// offRouteDistance distancia en km a la ruta a partir de la cual el conductor se considera desviado
+	offRouteDistance = 0.05
+	// offRouteUpdates posiciones consecutivas fuera de ruta antes de recalcular
+	offRouteUpdates = 2
+	// arrivalDistance distancia en km al destino para dar el viaje por terminado
+	arrivalDistance = 0.03
+	// rerouteCooldown tiempo mínimo entre recálculos de una misma sesión
+	rerouteCooldown = 10 * time.Second
+	// progressLookahead segmentos de la ruta revisados hacia adelante al ubicar al conductor
+	progressLookahead = 50
+	// maxTripPoints cantidad máxima de posiciones registradas por viaje
+	maxTripPoints = 20000
+)
+
+// ErrNoNavigation indica que el cliente no tiene un viaje activo
+var ErrNoNavigation = errors.New("no hay un viaje activo")
+
+// Estados de una sesión de navegación
+const (
+	NavigationActive   = "active"
+	NavigationOffRoute = "off_route"
+	NavigationRerouted = "rerouted"
+	NavigationArrived  = "arrived"
+)
+
+// Motivos de recálculo de ruta
+const (
+	RerouteDeviation = "deviation"
+	RerouteReport    = "report"
+)
+
+// NavigationSession viaje activo de un cliente: ruta vigente y progreso del conductor
+type NavigationSession struct {
+	ID          string
+	userID      int // conductor con sesión, 0 si es anónimo
+	request     RouteRequest
+	route       *models.Route
+	cumulative  []float64 // km acumulados hasta cada punto de la ruta
+	progress    int       // segmento de la ruta en el que está el conductor
+	traveled    float64   // km recorridos sobre la ruta vigente
+	position    models.Location
+	offRoute    int
+	lastReroute time.Time
+	arrived     bool
+	trace       []models.TracePoint // posiciones reportadas por el cliente
+	startedAt   time.Time
+	trip        *models.Trip // viaje registrado al terminar la sesión
+	mu          sync.Mutex
+}
+
+// NavigationService sigue el progreso de los viajes activos y recalcula sus rutas
+type NavigationService struct {
+	routing *RoutingService
+	nextID  int64
+}
+
+// NewNavigationService crea una nueva instancia del servicio de navegación
+func NewNavigationService(routing *RoutingService) *NavigationService {
+	return &NavigationService{
+		routing: routing,
+	}
+}
+
+// Start calcula la ruta inicial de un viaje de un usuario (0 si es anónimo) y crea su sesión
+func (ns *NavigationService) Start(req RouteRequest, userID int) (*NavigationSession, *models.NavigationUpdate, error) {
+	req.Alternatives = 0
+	route, err := ns.routing.CalculateRoute(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	session := &NavigationSession{
+		ID:        fmt.Sprintf("nav-%d", atomic.AddInt64(&ns.nextID, 1)),
+		userID:    userID,
+		request:   req,
+		position:  req.From,
+		trace:     []models.TracePoint{{Lat: req.From.Lat, Lng: req.From.Lng, Timestamp: now}},
+		startedAt: now,
+	}
+	session.setRoute(route)
+
+	update := session.update(NavigationActive)
+	update.Route = route
+	return session, update, nil
+}
+
+// UpdatePosition registra una nueva posición del conductor y recalcula la ruta si
+// se desvió durante varias posiciones consecutivas
+func (ns *NavigationService) UpdatePosition(session *NavigationSession, position models.Location) (*models.NavigationUpdate, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.arrived {
+		return session.update(NavigationArrived), nil
+	}
+	if len(session.trace) < maxTripPoints {
+		session.trace = append(session.trace, models.TracePoint{Lat: position.Lat, Lng: position.Lng, Timestamp: time.Now()})
+	}
+
+	distance := session.locate(position)
+	destination := session.route.To
+	if utils.HaversineDistance(position.Lat, position.Lng, destination.Lat, destination.Lng) <= arrivalDistance {
+		session.arrived = true
+		session.traveled = session.total()
+		return session.update(NavigationArrived), nil
+	}
+
+	if distance <= offRouteDistance {
+		session.offRoute = 0
+		return session.update(NavigationActive), nil
+	}
+
+	session.offRoute++
+	if session.offRoute < offRouteUpdates || time.Since(session.lastReroute) < rerouteCooldown {
+		return session.update(NavigationOffRoute), nil
+	}
+	return ns.reroute(session, position, RerouteDeviation)
+}
+
+// ReportAdded recalcula la ruta de una sesión si un nuevo reporte de incidente queda
+// sobre el tramo que falta recorrer. Retorna nil si la sesión no se ve afectada
+func (ns *NavigationService) ReportAdded(session *NavigationSession, report *models.Report) (*models.NavigationUpdate, error) {
+	if _, ok := incidentFactor(report); !ok {
+		return nil, nil
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.arrived || !session.ahead(report.Lat, report.Lng, incidentRadius) {
+		return nil, nil
+	}
+	return ns.reroute(session, session.position, RerouteReport)
+}
+
+// Finish termina una sesión y guarda el viaje recorrido. Llamadas sucesivas
+// retornan el mismo viaje
+func (ns *NavigationService) Finish(session *NavigationSession) *models.Trip {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.trip != nil {
+		return session.trip
+	}
+
+	distance := 0.0
+	for i := 1; i < len(session.trace); i++ {
+		a, b := session.trace[i-1], session.trace[i]
+		distance += utils.HaversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+	}
+
+	profile := session.request.Profile
+	if session.route != nil {
+		profile = session.route.Profile
+	}
+	trip := &models.Trip{
+		SessionID: session.ID,
+		Profile:   profile,
+		From:      models.Location{Lat: session.trace[0].Lat, Lng: session.trace[0].Lng},
+		To:        session.request.To,
+		Points:    session.trace,
+		Distance:  distance,
+		Arrived:   session.arrived,
+		StartedAt: session.startedAt,
+		EndedAt:   time.Now(),
+	}
+	saved, err := ns.routing.storage.SaveTrip(trip)
+	if err != nil {
+		// El viaje se retorna sin ID para que el cliente reciba igual su resumen
+		log.Printf("Error guardando el viaje de %s: %v", session.ID, err)
+		saved = trip
+	}
+	session.trip = saved
+
+	// Las velocidades del viaje alimentan los perfiles históricos, que usan todas las
+	// rutas: solo se aprende de los viajes de usuarios con sesión
+	if session.userID == 0 {
+		return session.trip
+	}
+	trace, tripID := session.trace, session.trip.ID
+	go func() {
+		if err := ns.routing.LearnTrace(trace, profile); err != nil {
+			log.Printf("Error ajustando el viaje %d a la red vial: %v", tripID, err)
+		}
+	}()
+	return session.trip
+}
+
+// reroute calcula una nueva ruta desde la posición actual hacia las paradas pendientes
+func (ns *NavigationService) reroute(session *NavigationSession, position models.Location, reason string) (*models.NavigationUpdate, error) {
+	req := session.request
+	req.From = position
+	req.Waypoints = session.pendingWaypoints()
+
+	route, err := ns.routing.CalculateRoute(req)
+	session.lastReroute = time.Now()
+	if err != nil {
+		return session.update(NavigationOffRoute), err
+	}
+
+	session.request = req
+	session.position = position
+	session.setRoute(route)
+
+	update := session.update(NavigationRerouted)
+	update.Reason = reason
+	update.Route = route
+	return update, nil
+}
+
+// setRoute reemplaza la ruta vigente y reinicia el progreso
+func (s *NavigationSession) setRoute(route *models.Route) {
+	s.route = route
+	s.progress = 0
+	s.traveled = 0
+	s.offRoute = 0
+	s.cumulative = make([]float64, len(route.Points))
+	for i := 1; i < len(route.Points); i++ {
+		a, b := route.Points[i-1], route.Points[i]
+		s.cumulative[i] = s.cumulative[i-1] + utils.HaversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+	}
+}
+
+// total distancia en km de la ruta vigente según su geometría
+func (s *NavigationSession) total() float64 {
+	if len(s.cumulative) == 0 {
+		return 0
+	}
+	return s.cumulative[len(s.cumulative)-1]
+}
+
+// locate ubica una posición sobre la ruta vigente, priorizando los segmentos próximos
+// al progreso actual. Retorna la distancia en km entre la posición y la ruta
+func (s *NavigationSession) locate(position models.Location) float64 {
+	best, bestAlong, bestDist := -1, 0.0, math.Inf(1)
+	search := func(from, to int) {
+		for i := from; i < to && i+1 < len(s.route.Points); i++ {
+			fraction, dist := projectOnSegment(position, s.route.Points[i], s.route.Points[i+1])
+			if dist < bestDist {
+				best, bestDist = i, dist
+				bestAlong = s.cumulative[i] + fraction*(s.cumulative[i+1]-s.cumulative[i])
+			}
+		}
+	}
+
+	search(s.progress, s.progress+progressLookahead)
+	if bestDist > offRouteDistance {
+		search(0, len(s.route.Points))
+	}
+
+	if best >= 0 && bestDist <= offRouteDistance {
+		s.progress = best
+		s.traveled = math.Max(s.traveled, bestAlong)
+	}
+	s.position = position
+	return bestDist
+}
+
+// ahead indica si un punto está a menos de radius km del tramo de ruta aún no recorrido
+func (s *NavigationSession) ahead(lat, lng, radius float64) bool {
+	point := models.Location{Lat: lat, Lng: lng}
+	for i := s.progress; i+1 < len(s.route.Points); i++ {
+		if _, dist := projectOnSegment(point, s.route.Points[i], s.route.Points[i+1]); dist <= radius {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingWaypoints paradas intermedias que el conductor aún no alcanzó
+func (s *NavigationSession) pendingWaypoints() []models.Location {
+	if len(s.route.Legs) == 0 {
+		return nil
+	}
+
+	// Escalar las distancias de los tramos a la geometría de la ruta
+	scale := 1.0
+	if s.route.Distance > 0 {
+		scale = s.total() / s.route.Distance
+	}
+
+	reached, legEnd := 0, 0.0
+	for i, leg := range s.route.Legs[:len(s.route.Legs)-1] {
+		legEnd += leg.Distance * scale
+		if s.traveled < legEnd {
+			break
+		}
+		reached = i + 1
+	}
+	return append([]models.Location(nil), s.route.Waypoints[reached:]...)
+}
+
+// update arma el estado de la sesión para el cliente
+func (s *NavigationSession) update(status string) *models.NavigationUpdate {
+	total := s.total()
+	remaining := math.Max(total-s.traveled, 0)
+	seconds := 0.0
+	if total > 0 {
+		seconds = float64(s.route.Duration) * 60 * remaining / total
+	}
+
+	update := &models.NavigationUpdate{
+		SessionID:         s.ID,
+		Status:            status,
+		Position:          s.position,
+		Traveled:          s.traveled,
+		Remaining:         remaining,
+		RemainingDuration: durationMinutes(seconds),
+		ETA:               time.Now().Add(time.Duration(seconds) * time.Second),
+	}
+	if status == NavigationArrived {
+		return update
+	}
+
+	// Próxima maniobra: el primer paso que empieza más adelante en la ruta
+	scale := 1.0
+	if s.route.Distance > 0 {
+		scale = total / s.route.Distance
+	}
+	start := 0.0
+	for i := range s.route.Steps {
+		if start > s.traveled {
+			step := s.route.Steps[i]
+			update.NextStep = &step
+			update.NextStepDistance = start - s.traveled
+			break
+		}
+		start += s.route.Steps[i].Distance * scale
+	}
+	return update
+}
+
+// projectOnSegment proyecta un punto sobre el segmento a-b con una aproximación
+// equirectangular. Retorna la fracción del segmento y la distancia en km al punto
+func projectOnSegment(p, a, b models.Location) (float64, float64) {
+	cosLat := math.Cos(p.Lat * math.Pi / 180)
+	ax, ay := (a.Lng-p.Lng)*cosLat, a.Lat-p.Lat
+	bx, by := (b.Lng-p.Lng)*cosLat, b.Lat-p.Lat
+	dx, dy := bx-ax, by-ay
+
+	fraction := 0.0
+	if lenSq := dx*dx + dy*dy; lenSq > 0 {
+		fraction = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lenSq))
+	}
+
+	px, py := ax+fraction*dx, ay+fraction*dy
+	return fraction, math.Sqrt(px*px+py*py) * 111.32
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gowaze/models"
+	"gowaze/utils"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxPhotosPerReport fotos máximas que acepta un reporte
+	MaxPhotosPerReport = 4
+	// MaxPhotoSize tamaño máximo en bytes de cada foto subida
+	MaxPhotoSize = 8 << 20
+	// maxPhotoPixels píxeles máximos de una foto subida, para no decodificar imágenes enormes
+	maxPhotoPixels = 40_000_000
+	// photoMaxDimension lado mayor en píxeles de la foto guardada
+	photoMaxDimension = 2048
+	// thumbnailSize lado mayor en píxeles de la miniatura
+	thumbnailSize = 320
+	// photoJPEGQuality calidad de las fotos y miniaturas JPEG guardadas
+	photoJPEGQuality = 85
+	// photoOrphanAge antigüedad desde la que se borran las fotos sin un reporte activo
+	photoOrphanAge = time.Hour
+	// photoCleanupInterval frecuencia del borrado de fotos huérfanas
+	photoCleanupInterval = time.Hour
+)
+
+// PhotosPath ruta desde la que se sirven las fotos
+const PhotosPath = "/photos/"
+
+var (
+	// ErrTooManyPhotos indica que se subieron más de MaxPhotosPerReport fotos
+	ErrTooManyPhotos = fmt.Errorf("máximo %d fotos por reporte", MaxPhotosPerReport)
+	// ErrPhotoTooLarge indica una foto que supera el tamaño o los píxeles máximos
+	ErrPhotoTooLarge = fmt.Errorf("foto demasiado grande: máximo %d MB y %d megapíxeles", MaxPhotoSize>>20, maxPhotoPixels/1_000_000)
+	// ErrUnsupportedPhoto indica una foto que no es JPEG ni PNG
+	ErrUnsupportedPhoto = errors.New("formato de foto no soportado: use JPEG o PNG")
+)
+
+// PhotoService procesa y guarda las fotos de los reportes. Cada foto se decodifica y
+// se vuelve a codificar, lo que descarta los metadatos EXIF (incluida la ubicación GPS)
+type PhotoService struct {
+	storage Storage
+	store   BlobStore
+}
+
+// NewPhotoService crea una nueva instancia del servicio de fotos
+func NewPhotoService(storage Storage, store BlobStore) *PhotoService {
+	return &PhotoService{
+		storage: storage,
+		store:   store,
+	}
+}
+
+// Save procesa y guarda las fotos subidas en un formulario multipart. Si alguna falla
+// no se guarda ninguna
+func (ps *PhotoService) Save(files []*multipart.FileHeader) ([]models.Photo, error) {
+	if len(files) > MaxPhotosPerReport {
+		return nil, ErrTooManyPhotos
+	}
+
+	photos := make([]models.Photo, 0, len(files))
+	for _, file := range files {
+		photo, err := ps.save(file)
+		if err != nil {
+			ps.remove(photos)
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+	return photos, nil
+}
+
+func (ps *PhotoService) save(file *multipart.FileHeader) (models.Photo, error) {
+	if file.Size > MaxPhotoSize {
+		return models.Photo{}, ErrPhotoTooLarge
+	}
+	f, err := file.Open()
+	if err != nil {
+		return models.Photo{}, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxPhotoSize+1))
+	if err != nil {
+		return models.Photo{}, err
+	}
+	if len(data) > MaxPhotoSize {
+		return models.Photo{}, ErrPhotoTooLarge
+	}
+
+	full, thumb, photo, err := processPhoto(data)
+	if err != nil {
+		return models.Photo{}, err
+	}
+
+	id, err := newPhotoID()
+	if err != nil {
+		return models.Photo{}, err
+	}
+	fullKey, thumbKey := photoKeys(id, photo.ContentType)
+	if err := ps.store.Put(fullKey, full); err != nil {
+		return models.Photo{}, err
+	}
+	if err := ps.store.Put(thumbKey, thumb); err != nil {
+		ps.store.Delete(fullKey)
+		return models.Photo{}, err
+	}
+
+	photo.ID = id
+	photo.URL = PhotosPath + fullKey
+	photo.ThumbnailURL = PhotosPath + thumbKey
+	return photo, nil
+}
+
+// processPhoto decodifica una foto JPEG o PNG, la endereza según su orientación EXIF,
+// la reduce a photoMaxDimension y genera su miniatura. Retorna ambas ya codificadas
+func processPhoto(data []byte) ([]byte, []byte, models.Photo, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png") {
+		return nil, nil, models.Photo{}, ErrUnsupportedPhoto
+	}
+	if config.Width*config.Height > maxPhotoPixels {
+		return nil, nil, models.Photo{}, ErrPhotoTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, models.Photo{}, ErrUnsupportedPhoto
+	}
+
+	rgba := utils.ToRGBA(img)
+	if format == "jpeg" {
+		rgba = utils.Orient(rgba, utils.ExifOrientation(data))
+	}
+	rgba = utils.Fit(rgba, photoMaxDimension)
+
+	full, err := encodePhoto(rgba, format)
+	if err != nil {
+		return nil, nil, models.Photo{}, err
+	}
+	thumb, err := encodePhoto(utils.Fit(rgba, thumbnailSize), format)
+	if err != nil {
+		return nil, nil, models.Photo{}, err
+	}
+
+	return full, thumb, models.Photo{
+		ContentType: "image/" + format,
+		Width:       rgba.Rect.Dx(),
+		Height:      rgba.Rect.Dy(),
+		Size:        len(full),
+	}, nil
+}
+
+func encodePhoto(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: photoJPEGQuality})
+	}
+	return buf.Bytes(), err
+}
+
+func newPhotoID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// photoKeys claves de la foto y de su miniatura en el almacén de archivos
+func photoKeys(id, contentType string) (string, string) {
+	ext := ".jpg"
+	if contentType == "image/png" {
+		ext = ".png"
+	}
+	return id + ext, id + "_thumb" + ext
+}
+
+// remove borra fotos ya guardadas
+func (ps *PhotoService) remove(photos []models.Photo) {
+	for _, photo := range photos {
+		fullKey, thumbKey := photoKeys(photo.ID, photo.ContentType)
+		ps.store.Delete(fullKey)
+		ps.store.Delete(thumbKey)
+	}
+}
+
+// Open obtiene una foto o miniatura por su clave y su tipo de contenido
+func (ps *PhotoService) Open(key string) ([]byte, string, error) {
+	data, err := ps.store.Get(key)
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := "image/jpeg"
+	if path.Ext(key) == ".png" {
+		contentType = "image/png"
+	}
+	return data, contentType, nil
+}
+
+// StartCleanup borra periódicamente las fotos que no pertenecen a ningún reporte
+// activo, como las de reportes vencidos o retirados
+func (ps *PhotoService) StartCleanup() {
+	ticker := time.NewTicker(photoCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ps.cleanup()
+	}
+}
+
+func (ps *PhotoService) cleanup() {
+	active := make(map[string]bool)
+	for _, report := range ps.storage.GetRecentReports() {
+		for _, photo := range report.Photos {
+			active[photo.ID] = true
+		}
+	}
+
+	blobs, err := ps.store.List()
+	if err != nil {
+		log.Printf("Error listando fotos: %v", err)
+		return
+	}
+	removed := 0
+	for _, blob := range blobs {
+		id := strings.TrimSuffix(strings.TrimSuffix(blob.Key, path.Ext(blob.Key)), "_thumb")
+		// Las fotos recientes pueden pertenecer a un reporte que aún se está creando
+		if active[id] || time.Since(blob.UpdatedAt) < photoOrphanAge {
+			continue
+		}
+		if err := ps.store.Delete(blob.Key); err != nil {
+			log.Printf("Error borrando foto %s: %v", blob.Key, err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("🧹 Fotos huérfanas borradas: %d", removed)
+	}
+}
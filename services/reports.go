@@ -0,0 +1,95 @@
+package services
+
+import (
+	"gowaze/models"
+	"gowaze/utils"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultReportsLimit cantidad de reportes por página si no se indica otra
+	DefaultReportsLimit = 100
+	// MaxReportsLimit cantidad máxima de reportes por página
+	MaxReportsLimit = 500
+	// MaxReportsRadius radio máximo en km de una búsqueda de reportes
+	MaxReportsRadius = 50.0
+	// mergeRadius distancia en km dentro de la cual dos reportes del mismo tipo son el mismo incidente
+	mergeRadius = 0.1
+	// mergeWindow tiempo desde la última actividad de un incidente en que se le agrupan duplicados
+	mergeWindow = 30 * time.Minute
+	// RetireDismissals votos "ya no está" seguidos, sin confirmaciones en medio, que retiran un reporte
+	RetireDismissals = 3
+)
+
+// ReportQuery filtros de una búsqueda de reportes activos. Los campos vacíos no filtran
+type ReportQuery struct {
+	BBox     *utils.BBox      // rectángulo visible del mapa
+	Center   *models.Location // centro de una búsqueda por radio
+	Radius   float64          // radio en km alrededor de Center
+	Types    []string         // tipos de reporte aceptados
+	Since    time.Time        // creados desde
+	Until    time.Time        // creados hasta
+	MinVotes int              // votos mínimos
+	Offset   int
+	Limit    int
+}
+
+// FindReports busca los reportes activos que cumplen los filtros usando el índice
+// espacial. Con centro se ordenan del más cercano al más lejano; si no, del más
+// reciente al más antiguo. Retorna la página pedida y el total de coincidencias
+func FindReports(storage Storage, q ReportQuery) ([]*models.Report, int) {
+	var candidates []*models.Report
+	switch {
+	case q.Center != nil:
+		candidates = storage.ReportsWithin(q.Center.Lat, q.Center.Lng, q.Radius)
+	case q.BBox != nil:
+		candidates = storage.ReportsInBox(*q.BBox)
+	default:
+		candidates = storage.GetRecentReports()
+	}
+
+	types := make(map[string]bool, len(q.Types))
+	for _, t := range q.Types {
+		types[t] = true
+	}
+
+	reports := make([]*models.Report, 0, len(candidates))
+	for _, report := range candidates {
+		switch {
+		case q.Center != nil && q.BBox != nil && !q.BBox.Contains(report.Lat, report.Lng):
+		case len(types) > 0 && !types[report.Type]:
+		case !q.Since.IsZero() && report.CreatedAt.Before(q.Since):
+		case !q.Until.IsZero() && report.CreatedAt.After(q.Until):
+		case report.Votes < q.MinVotes:
+		default:
+			reports = append(reports, report)
+		}
+	}
+
+	if q.Center == nil {
+		sort.SliceStable(reports, func(i, j int) bool {
+			if !reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+				return reports[i].CreatedAt.After(reports[j].CreatedAt)
+			}
+			return reports[i].ID > reports[j].ID
+		})
+	}
+
+	total := len(reports)
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultReportsLimit
+	}
+	if limit > MaxReportsLimit {
+		limit = MaxReportsLimit
+	}
+	if q.Offset >= total {
+		return []*models.Report{}, total
+	}
+	end := q.Offset + limit
+	if end > total {
+		end = total
+	}
+	return reports[q.Offset:end], total
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"gowaze/models"
+	"gowaze/utils"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testReports almacenamiento con cinco reportes sobre un mismo meridiano, creados con
+// diez minutos de diferencia: del 1 (el más antiguo) al 5 (el más reciente, a ~13 km
+// del resto). El 2 tiene tres votos
+func testReports(t *testing.T, now time.Time) *MemoryStorage {
+	s := NewMemoryStorage()
+	drafts := []models.Report{
+		{Type: "accident", Lat: 14.0800, Lng: -87.21},
+		{Type: "police", Lat: 14.0850, Lng: -87.21},
+		{Type: "hazard", Lat: 14.0900, Lng: -87.21},
+		{Type: "traffic", Lat: 14.1000, Lng: -87.21},
+		{Type: "accident", Lat: 14.2000, Lng: -87.21},
+	}
+	for i := range drafts {
+		report, _, err := s.CreateReport(&drafts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Reports[report.ID].CreatedAt = now.Add(time.Duration(i-5) * 10 * time.Minute)
+	}
+	for _, userID := range []int{2, 3} {
+		if _, _, err := s.VoteReport(2, userID, VoteUp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestFindReports(t *testing.T) {
+	now := time.Now()
+	center := &models.Location{Lat: 14.0801, Lng: -87.21}
+
+	tests := []struct {
+		name      string
+		query     ReportQuery
+		wantIDs   []int
+		wantTotal int
+	}{
+		{"sin filtros, del más reciente al más antiguo", ReportQuery{}, []int{5, 4, 3, 2, 1}, 5},
+		{
+			name:      "rectángulo",
+			query:     ReportQuery{BBox: &utils.BBox{MinLat: 14.075, MinLng: -87.22, MaxLat: 14.095, MaxLng: -87.20}},
+			wantIDs:   []int{3, 2, 1},
+			wantTotal: 3,
+		},
+		{
+			name:      "radio, del más cercano al más lejano",
+			query:     ReportQuery{Center: center, Radius: 2},
+			wantIDs:   []int{1, 2, 3},
+			wantTotal: 3,
+		},
+		{
+			name:      "radio y rectángulo",
+			query:     ReportQuery{Center: center, Radius: 2, BBox: &utils.BBox{MinLat: 14.083, MinLng: -87.22, MaxLat: 14.3, MaxLng: -87.20}},
+			wantIDs:   []int{2, 3},
+			wantTotal: 2,
+		},
+		{"tipos", ReportQuery{Types: []string{"accident", "traffic"}}, []int{5, 4, 1}, 3},
+		{
+			name:      "desde y hasta",
+			query:     ReportQuery{Since: now.Add(-45 * time.Minute), Until: now.Add(-15 * time.Minute)},
+			wantIDs:   []int{4, 3, 2},
+			wantTotal: 3,
+		},
+		{"votos mínimos", ReportQuery{MinVotes: 2}, []int{2}, 1},
+		{"página", ReportQuery{Offset: 1, Limit: 2}, []int{4, 3}, 5},
+		{"última página incompleta", ReportQuery{Offset: 4, Limit: 2}, []int{1}, 5},
+		{"página fuera de rango", ReportQuery{Offset: 10}, []int{}, 5},
+		{"sin coincidencias", ReportQuery{Types: []string{"closure"}}, []int{}, 0},
+	}
+
+	s := testReports(t, now)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, total := FindReports(s, tt.query)
+			ids := make([]int, len(reports))
+			for i, report := range reports {
+				ids[i] = report.ID
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || total != tt.wantTotal {
+				t.Errorf("FindReports = %v (total %d), esperado %v (total %d)", ids, total, tt.wantIDs, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestFindReportsLimit(t *testing.T) {
+	s := NewMemoryStorage()
+	for i := 0; i < MaxReportsLimit+10; i++ {
+		// Separados por ~1 km para que no se agrupen
+		draft := &models.Report{Type: "accident", Lat: 14 + float64(i)*0.01, Lng: -87}
+		if _, _, err := s.CreateReport(draft); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"por defecto", 0, DefaultReportsLimit},
+		{"indicado", 7, 7},
+		{"mayor que el máximo", MaxReportsLimit * 2, MaxReportsLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, total := FindReports(s, ReportQuery{Limit: tt.limit})
+			if len(reports) != tt.want || total != MaxReportsLimit+10 {
+				t.Errorf("Limit %d retornó %d reportes (total %d), esperados %d (total %d)",
+					tt.limit, len(reports), total, tt.want, MaxReportsLimit+10)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gowaze/models"
+	"sort"
+	"time"
+)
+
+// Gravedad de un reporte
+const (
+	MinSeverity = 1 // leve
+	MaxSeverity = 5 // crítica
+)
+
+// MaxLanes carril más alto que puede indicar un reporte
+const MaxLanes = 8
+
+// DirectionBoth sentido de un reporte que afecta a ambos sentidos de circulación
+const DirectionBoth = "both"
+
+// Directions sentidos de circulación que puede indicar un reporte
+var Directions = []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW", DirectionBoth}
+
+// ErrInvalidReportType indica un tipo o subtipo de reporte que no está en el registro
+var ErrInvalidReportType = errors.New("tipo de reporte inválido")
+
+// ReportSubtype variante de un tipo de reporte. Icon, Lifetime y Severity vacíos
+// heredan los del tipo
+type ReportSubtype struct {
+	Name     string        `json:"name"`
+	Label    string        `json:"label"`
+	Icon     string        `json:"icon,omitempty"`
+	Lifetime time.Duration `json:"-"`
+	Severity int           `json:"severity,omitempty"`
+}
+
+// ReportType tipo de reporte con su icono, vida por defecto desde la última
+// confirmación y gravedad por defecto
+type ReportType struct {
+	Name     string           `json:"name"`
+	Label    string           `json:"label"`
+	Icon     string           `json:"icon"`
+	Lifetime time.Duration    `json:"-"`
+	Severity int              `json:"severity"`
+	Subtypes []*ReportSubtype `json:"subtypes,omitempty"`
+}
+
+// reportTypes registro de tipos de reporte aceptados, en el orden en que se ofrecen
+var reportTypes = []*ReportType{
+	{Name: "accident", Label: "Accidente", Icon: "🚗", Lifetime: 2 * time.Hour, Severity: 4, Subtypes: []*ReportSubtype{
+		{Name: "minor", Label: "Leve", Severity: 3},
+		{Name: "major", Label: "Grave", Icon: "🚑", Lifetime: 3 * time.Hour, Severity: 5},
+	}},
+	{Name: "police", Label: "Control Policial", Icon: "👮", Lifetime: time.Hour, Severity: 2, Subtypes: []*ReportSubtype{
+		{Name: "visible", Label: "Visible"},
+		{Name: "hidden", Label: "Oculto"},
+		{Name: "checkpoint", Label: "Retén", Lifetime: 2 * time.Hour, Severity: 3},
+	}},
+	{Name: "traffic", Label: "Congestión de Tráfico", Icon: "🚦", Lifetime: time.Hour, Severity: 3, Subtypes: []*ReportSubtype{
+		{Name: "moderate", Label: "Moderada", Severity: 2},
+		{Name: "heavy", Label: "Densa", Severity: 3},
+		{Name: "standstill", Label: "Detenido", Icon: "🛑", Lifetime: 30 * time.Minute, Severity: 4},
+	}},
+	{Name: "hazard", Label: "Peligro en la Vía", Icon: "⚠️", Lifetime: 6 * time.Hour, Severity: 3, Subtypes: []*ReportSubtype{
+		{Name: "pothole", Label: "Bache", Icon: "🕳️", Lifetime: 24 * time.Hour, Severity: 2},
+		{Name: "object_on_road", Label: "Objeto en la vía", Icon: "📦", Lifetime: time.Hour, Severity: 3},
+		{Name: "flooding", Label: "Inundación", Icon: "🌊", Lifetime: 12 * time.Hour, Severity: 4},
+		{Name: "broken_traffic_light", Label: "Semáforo dañado", Icon: "🚥", Lifetime: 12 * time.Hour, Severity: 3},
+	}},
+	{Name: "closure", Label: "Cierre de Vía", Icon: "🚧", Lifetime: 6 * time.Hour, Severity: 5, Subtypes: []*ReportSubtype{
+		{Name: "roadworks", Label: "Obras", Lifetime: 24 * time.Hour},
+		{Name: "event", Label: "Evento"},
+		{Name: "accident", Label: "Por accidente", Lifetime: 2 * time.Hour},
+	}},
+}
+
+// MarshalJSON publica la vida del subtipo en minutos, omitida si hereda la del tipo
+func (sub *ReportSubtype) MarshalJSON() ([]byte, error) {
+	type plain ReportSubtype
+	return json.Marshal(struct {
+		*plain
+		LifetimeMinutes int `json:"lifetime_minutes,omitempty"`
+	}{(*plain)(sub), int(sub.Lifetime / time.Minute)})
+}
+
+// MarshalJSON publica la vida del tipo en minutos
+func (t *ReportType) MarshalJSON() ([]byte, error) {
+	type plain ReportType
+	return json.Marshal(struct {
+		*plain
+		LifetimeMinutes int `json:"lifetime_minutes"`
+	}{(*plain)(t), int(t.Lifetime / time.Minute)})
+}
+
+// ReportTypes retorna el registro de tipos de reporte. No debe modificarse
+func ReportTypes() []*ReportType {
+	return reportTypes
+}
+
+// LookupReportType busca un tipo de reporte y, si se indica, uno de sus subtipos
+func LookupReportType(name, subtype string) (*ReportType, *ReportSubtype, bool) {
+	for _, t := range reportTypes {
+		if t.Name != name {
+			continue
+		}
+		if subtype == "" {
+			return t, nil, true
+		}
+		for _, sub := range t.Subtypes {
+			if sub.Name == subtype {
+				return t, sub, true
+			}
+		}
+		return nil, nil, false
+	}
+	return nil, nil, false
+}
+
+// ReportIcon retorna el icono de un tipo y subtipo de reporte
+func ReportIcon(reportType, subtype string) string {
+	t, sub, ok := LookupReportType(reportType, subtype)
+	switch {
+	case !ok:
+		return "📍"
+	case sub != nil && sub.Icon != "":
+		return sub.Icon
+	}
+	return t.Icon
+}
+
+// ReportLabel retorna el nombre legible de un tipo y subtipo de reporte
+func ReportLabel(reportType, subtype string) string {
+	t, sub, ok := LookupReportType(reportType, subtype)
+	switch {
+	case !ok:
+		return reportType
+	case sub != nil:
+		return t.Label + " - " + sub.Label
+	}
+	return t.Label
+}
+
+// defaultSeverity gravedad de un tipo y subtipo de reporte cuando quien reporta no la indica
+func defaultSeverity(reportType, subtype string) int {
+	t, sub, ok := LookupReportType(reportType, subtype)
+	switch {
+	case !ok:
+		return MinSeverity
+	case sub != nil && sub.Severity != 0:
+		return sub.Severity
+	}
+	return t.Severity
+}
+
+// ValidateReport comprueba el tipo, subtipo, gravedad, sentido y carriles de un
+// reporte por crear y completa la gravedad por defecto si no se indicó
+func ValidateReport(report *models.Report) error {
+	if _, _, ok := LookupReportType(report.Type, report.Subtype); !ok {
+		return ErrInvalidReportType
+	}
+
+	if report.Severity == 0 {
+		report.Severity = defaultSeverity(report.Type, report.Subtype)
+	}
+	if report.Severity < MinSeverity || report.Severity > MaxSeverity {
+		return fmt.Errorf("gravedad inválida: debe estar entre %d y %d", MinSeverity, MaxSeverity)
+	}
+
+	if report.Direction != "" && !containsString(Directions, report.Direction) {
+		return fmt.Errorf("sentido inválido %q", report.Direction)
+	}
+
+	seen := make(map[int]bool, len(report.Lanes))
+	lanes := make([]int, 0, len(report.Lanes))
+	for _, lane := range report.Lanes {
+		if lane < 1 || lane > MaxLanes {
+			return fmt.Errorf("carril inválido %d: debe estar entre 1 y %d", lane, MaxLanes)
+		}
+		if !seen[lane] {
+			seen[lane] = true
+			lanes = append(lanes, lane)
+		}
+	}
+	sort.Ints(lanes)
+	report.Lanes = lanes
+	return nil
+}
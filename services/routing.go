@@ -0,0 +1,690 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gowaze/models"
+	"gowaze/routing"
+	"gowaze/utils"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// trafficRadius radio en km alrededor de un punto de tráfico cuyos tramos toman su velocidad
+	trafficRadius = 0.3
+	// trafficMaxAge antigüedad máxima de un dato de tráfico para considerarlo en vivo
+	trafficMaxAge = 10 * time.Minute
+	// incidentRadius radio en km alrededor de un reporte que afecta la velocidad de los tramos
+	incidentRadius = 0.15
+	// MaxAlternatives cantidad máxima de rutas alternativas por cálculo
+	MaxAlternatives = 3
+	// MaxWaypoints cantidad máxima de paradas intermedias por ruta
+	MaxWaypoints = 10
+	// MaxMatrixLocations cantidad máxima de orígenes o destinos de una matriz
+	MaxMatrixLocations = 100
+	// matrixWorkers cantidad de búsquedas concurrentes al calcular una matriz
+	matrixWorkers = 4
+	// MaxTracePoints cantidad máxima de puntos de una traza a ajustar
+	MaxTracePoints = 500
+	// MaxIsochroneMinutes tiempo máximo de viaje de una isócrona
+	MaxIsochroneMinutes = 60
+	// MaxAvoidRadius radio máximo en km alrededor de los reportes a evitar
+	MaxAvoidRadius = 5.0
+	// arriveByIterations recálculos máximos para ajustar la salida a una hora de llegada
+	arriveByIterations = 5
+	// arriveByTolerance diferencia aceptada entre la llegada estimada y la solicitada
+	arriveByTolerance = time.Minute
+	// historySaveInterval frecuencia con que se guarda el historial de velocidades
+	historySaveInterval = 10 * time.Minute
+)
+
+// DefaultIsochroneMinutes tiempos por defecto de las isócronas
+var DefaultIsochroneMinutes = []int{5, 10, 15}
+
+// incidentFactors reducción de velocidad por tipo o "tipo/subtipo" de reporte activo.
+// El subtipo tiene prioridad sobre el tipo
+var incidentFactors = map[string]float64{
+	"accident":           0.3,
+	"accident/major":     0.15,
+	"traffic":            0.5,
+	"traffic/moderate":   0.7,
+	"traffic/heavy":      0.4,
+	"traffic/standstill": 0.15,
+	"hazard/flooding":    0.4,
+	"closure":            0.05,
+}
+
+// incidentFactor retorna la reducción de velocidad que causa un reporte
+func incidentFactor(report *models.Report) (float64, bool) {
+	if report.Subtype != "" {
+		if factor, ok := incidentFactors[report.Type+"/"+report.Subtype]; ok {
+			return factor, true
+		}
+	}
+	factor, ok := incidentFactors[report.Type]
+	return factor, ok
+}
+
+// RoutingService calcula rutas sobre la red vial cargada
+type RoutingService struct {
+	storage      Storage
+	router       *routing.Router
+	history      *routing.SpeedHistory
+	savedVersion uint64     // versión del historial guardada por última vez
+	saveMu       sync.Mutex // serializa los guardados del historial
+}
+
+// NewRoutingService crea una nueva instancia del servicio de rutas con el historial
+// de velocidades guardado en el almacenamiento
+func NewRoutingService(storage Storage, graph *routing.Graph) *RoutingService {
+	rs := &RoutingService{
+		storage: storage,
+		router:  routing.NewRouter(graph),
+		history: routing.NewSpeedHistory(graph),
+	}
+	if data, err := storage.LoadSpeedHistory(); err != nil {
+		log.Printf("Error cargando historial de velocidades: %v", err)
+	} else if data != nil {
+		edges := rs.history.Load(data)
+		log.Printf("📈 Historial de velocidades recuperado: %d tramos, %d clases de vía", edges, len(data.Classes))
+	}
+	return rs
+}
+
+// History retorna el historial de velocidades aprendido de los viajes
+func (rs *RoutingService) History() *routing.SpeedHistory {
+	return rs.history
+}
+
+// StartHistorySaves guarda periódicamente el historial de velocidades
+func (rs *RoutingService) StartHistorySaves() {
+	ticker := time.NewTicker(historySaveInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := rs.SaveHistory(); err != nil {
+			log.Printf("Error guardando historial de velocidades: %v", err)
+		}
+	}
+}
+
+// SaveHistory guarda el historial de velocidades si cambió desde el último guardado
+func (rs *RoutingService) SaveHistory() error {
+	rs.saveMu.Lock()
+	defer rs.saveMu.Unlock()
+
+	version := rs.history.Version()
+	if version == rs.savedVersion {
+		return nil
+	}
+	if err := rs.storage.SaveSpeedHistory(rs.history.Data()); err != nil {
+		return err
+	}
+	rs.savedVersion = version
+	return nil
+}
+
+// HasGraph indica si hay una red vial disponible para calcular rutas
+func (rs *RoutingService) HasGraph() bool {
+	return !rs.router.Graph().Empty()
+}
+
+// RouteRequest parámetros para el cálculo de una ruta
+type RouteRequest struct {
+	From         models.Location
+	To           models.Location
+	Waypoints    []models.Location  // paradas intermedias
+	Optimize     bool               // reordenar las paradas para minimizar la duración total
+	Alternatives int                // cantidad máxima de rutas alternativas
+	Language     string             // idioma de las instrucciones ("es" o "en")
+	Profile      string             // perfil de viaje: car, motorcycle, truck, bicycle, walking
+	Height       float64            // altura del vehículo en metros (camiones)
+	Weight       float64            // peso del vehículo en toneladas (camiones)
+	Avoid        routing.Avoid      // peajes, vías sin pavimentar y zonas a evitar
+	AvoidReports map[string]float64 // radio en km a evitar alrededor de cada tipo de reporte
+	Precision    int                // precisión de la polilínea codificada (5 o 6)
+	OmitPoints   bool               // omitir la lista de puntos y enviar solo la geometría compacta
+	DepartAt     time.Time          // hora de salida; si es cero se sale ahora
+	ArriveBy     time.Time          // hora de llegada deseada; tiene prioridad sobre DepartAt
+}
+
+// routePlan condiciones con las que se calcula una ruta
+type routePlan struct {
+	profile   *routing.Profile
+	traffic   *routing.Traffic
+	avoid     *routing.Avoid
+	depart    time.Time
+	weighting routing.Weighting // pesos para una salida en depart
+	lang      string
+}
+
+// at retorna los pesos para una salida en otro instante, como el inicio de un tramo
+// intermedio de una ruta multiparada
+func (p *routePlan) at(router *routing.Router, history *routing.SpeedHistory, depart time.Time) routing.Weighting {
+	return router.Avoiding(router.TimeWeighting(p.profile, p.traffic, history, depart), p.avoid)
+}
+
+// newPlan resuelve el perfil y el tráfico aplicables a una solicitud
+func (rs *RoutingService) newPlan(req RouteRequest) (*routePlan, error) {
+	profile, err := routing.ProfileByName(req.Profile)
+	if err != nil {
+		return nil, err
+	}
+	profile.Height = req.Height
+	profile.Weight = req.Weight
+
+	now := time.Now()
+	plan := &routePlan{profile: profile, depart: req.DepartAt, lang: req.Language}
+	if plan.depart.IsZero() {
+		plan.depart = now
+	}
+	// El tráfico en vivo solo importa si la salida es cercana
+	if profile.Motorized && plan.depart.Before(now.Add(routing.LiveHorizon)) && plan.depart.After(now.Add(-routing.LiveHorizon)) {
+		plan.traffic = rs.currentTraffic()
+	}
+
+	avoid := req.Avoid
+	avoid.Circles = append([]routing.Circle(nil), req.Avoid.Circles...)
+	if len(req.AvoidReports) > 0 {
+		for _, report := range rs.storage.GetRecentReports() {
+			radius, ok := req.AvoidReports[report.Type]
+			if !ok {
+				continue
+			}
+			avoid.Circles = append(avoid.Circles, routing.Circle{
+				Center: models.Location{Lat: report.Lat, Lng: report.Lng},
+				Radius: math.Min(radius, MaxAvoidRadius),
+			})
+		}
+	}
+	plan.avoid = &avoid
+	plan.weighting = plan.at(rs.router, rs.history, plan.depart)
+	return plan, nil
+}
+
+// CalculateRoute calcula la ruta más rápida entre dos ubicaciones siguiendo la red vial,
+// junto con las alternativas solicitadas. Las velocidades siguen los perfiles históricos
+// de la hora de salida y, si se sale ahora, el tráfico en vivo. Con ArriveBy se busca
+// la salida más tardía que llega a tiempo
+func (rs *RoutingService) CalculateRoute(req RouteRequest) (*models.Route, error) {
+	var route *models.Route
+	var err error
+	if req.ArriveBy.IsZero() {
+		route, err = rs.calculateRoute(req)
+	} else {
+		route, err = rs.arriveBy(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	SetGeometry(route, req.Precision, req.OmitPoints)
+	return route, nil
+}
+
+// SetGeometry agrega a una ruta y a sus alternativas la polilínea codificada y la
+// geometría GeoJSON. Con omitPoints se descarta la lista de puntos
+func SetGeometry(route *models.Route, precision int, omitPoints bool) {
+	if precision != utils.PolylinePrecision6 {
+		precision = utils.PolylinePrecision
+	}
+
+	geometry := utils.LineString(route.Points)
+	route.Polyline = utils.EncodePolyline(route.Points, precision)
+	route.Precision = precision
+	route.Geometry = &geometry
+	if omitPoints {
+		route.Points = nil
+	}
+
+	for i := range route.Alternatives {
+		SetGeometry(&route.Alternatives[i], precision, omitPoints)
+	}
+}
+
+// arriveBy ajusta la hora de salida hasta que la llegada estimada coincida con la
+// solicitada, ya que la duración del viaje depende de la hora en que se sale
+func (rs *RoutingService) arriveBy(req RouteRequest) (*models.Route, error) {
+	req.DepartAt = req.ArriveBy
+	var route *models.Route
+	for i := 0; i < arriveByIterations; i++ {
+		var err error
+		route, err = rs.calculateRoute(req)
+		if err != nil {
+			return nil, err
+		}
+
+		late := route.ArriveAt.Sub(req.ArriveBy)
+		if late <= 0 && late > -arriveByTolerance {
+			break
+		}
+		req.DepartAt = req.DepartAt.Add(-late)
+	}
+	return route, nil
+}
+
+// Schedule completa la salida y la llegada de una ruta a partir de su duración
+// estimada, para rutas calculadas sin red vial
+func Schedule(route *models.Route, req RouteRequest) {
+	travel := time.Duration(route.Duration) * time.Minute
+	switch {
+	case !req.ArriveBy.IsZero():
+		route.ArriveAt = req.ArriveBy
+		route.DepartAt = req.ArriveBy.Add(-travel)
+	case !req.DepartAt.IsZero():
+		route.DepartAt = req.DepartAt
+		route.ArriveAt = req.DepartAt.Add(travel)
+	default:
+		route.DepartAt = time.Now()
+		route.ArriveAt = route.DepartAt.Add(travel)
+	}
+}
+
+// calculateRoute calcula la ruta principal y sus alternativas o, con paradas, la ruta multiparada
+func (rs *RoutingService) calculateRoute(req RouteRequest) (*models.Route, error) {
+	if len(req.Waypoints) > MaxWaypoints {
+		return nil, fmt.Errorf("máximo %d paradas intermedias", MaxWaypoints)
+	}
+
+	plan, err := rs.newPlan(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Waypoints) > 0 {
+		return rs.calculateMultiStop(req, plan)
+	}
+
+	alternatives := req.Alternatives
+	if alternatives < 0 {
+		alternatives = 0
+	}
+	if alternatives > MaxAlternatives {
+		alternatives = MaxAlternatives
+	}
+
+	paths, err := rs.router.Alternatives(req.From, req.To, plan.weighting, alternatives)
+	if err != nil {
+		return nil, err
+	}
+
+	route := rs.buildRoute([]models.Location{req.From, req.To}, paths[:1], plan)
+	for i, path := range paths[1:] {
+		alt := rs.buildRoute([]models.Location{req.From, req.To}, []*routing.Path{path}, plan)
+		alt.ID = i + 1
+		route.Alternatives = append(route.Alternatives, *alt)
+	}
+	return route, nil
+}
+
+// MatrixRequest parámetros para el cálculo de una matriz de tiempos y distancias
+type MatrixRequest struct {
+	Origins      []models.Location
+	Destinations []models.Location // si está vacío se usan los orígenes
+	Profile      string
+	Avoid        routing.Avoid
+	AvoidReports map[string]float64
+}
+
+// Matrix calcula el tiempo y la distancia de viaje entre cada origen y cada destino.
+// Cada origen se resuelve con una búsqueda de uno a muchos repartida entre un
+// número acotado de workers
+func (rs *RoutingService) Matrix(req MatrixRequest) (*models.Matrix, error) {
+	destinations := req.Destinations
+	if len(destinations) == 0 {
+		destinations = req.Origins
+	}
+	if len(req.Origins) == 0 {
+		return nil, fmt.Errorf("se requiere al menos un origen")
+	}
+	if len(req.Origins) > MaxMatrixLocations || len(destinations) > MaxMatrixLocations {
+		return nil, fmt.Errorf("máximo %d orígenes y %d destinos", MaxMatrixLocations, MaxMatrixLocations)
+	}
+
+	plan, err := rs.newPlan(RouteRequest{Profile: req.Profile, Avoid: req.Avoid, AvoidReports: req.AvoidReports})
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &models.Matrix{
+		Origins:      req.Origins,
+		Destinations: destinations,
+		Durations:    make([][]*float64, len(req.Origins)),
+		Distances:    make([][]*float64, len(req.Origins)),
+		Profile:      plan.profile.Name,
+	}
+
+	jobs := make(chan int)
+	errs := make(chan error, len(req.Origins))
+	var wg sync.WaitGroup
+	for w := 0; w < matrixWorkers && w < len(req.Origins); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				cells, err := rs.router.OneToMany(req.Origins[i], destinations, plan.weighting)
+				if err != nil && !errors.Is(err, routing.ErrNoSnap) {
+					errs <- err
+					continue
+				}
+
+				// Cada worker escribe solo su fila
+				matrix.Durations[i] = make([]*float64, len(destinations))
+				matrix.Distances[i] = make([]*float64, len(destinations))
+				for j, cell := range cells {
+					if !cell.Reachable {
+						continue
+					}
+					duration := math.Round(cell.Duration)
+					distance := math.Round(cell.Distance*1000) / 1000
+					matrix.Durations[i][j] = &duration
+					matrix.Distances[i][j] = &distance
+				}
+			}
+		}()
+	}
+
+	for i := range req.Origins {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return matrix, nil
+}
+
+// MatchTrace ajusta una traza GPS a la red vial y calcula los tiempos de cada tramo
+// recorrido. Los puntos se ordenan por tiempo antes del ajuste. No alimenta los
+// perfiles históricos: la traza puede venir de cualquiera
+func (rs *RoutingService) MatchTrace(trace []models.TracePoint, profileName string) (*models.MatchedTrace, error) {
+	if len(trace) > MaxTracePoints {
+		return nil, fmt.Errorf("máximo %d puntos por traza", MaxTracePoints)
+	}
+	profile, err := routing.ProfileByName(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := append([]models.TracePoint(nil), trace...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	return rs.router.Match(sorted, profile)
+}
+
+// LearnTrace ajusta a la red vial una traza de cualquier largo, en tramos de hasta
+// MaxTracePoints puntos que comparten su punto de unión, para alimentar los perfiles
+// históricos. Solo debe recibir viajes de usuarios con sesión. Sin red vial o con un
+// perfil no motorizado no hace nada. Retorna el primer error de ajuste
+func (rs *RoutingService) LearnTrace(trace []models.TracePoint, profileName string) error {
+	profile, err := routing.ProfileByName(profileName)
+	if err != nil {
+		return err
+	}
+	if !profile.Motorized || rs.router.Graph().Empty() || len(trace) < 2 {
+		return nil
+	}
+
+	sorted := append([]models.TracePoint(nil), trace...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var first error
+	for start := 0; start < len(sorted)-1; start += MaxTracePoints - 1 {
+		end := start + MaxTracePoints
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+		matched, err := rs.router.Match(sorted[start:end], profile)
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("puntos %d-%d: %w", start, end-1, err)
+			}
+			continue
+		}
+		rs.observe(matched)
+	}
+	return first
+}
+
+// observe agrega las velocidades de una traza ajustada a los perfiles históricos
+func (rs *RoutingService) observe(matched *models.MatchedTrace) {
+	for _, seg := range matched.Segments {
+		rs.history.Observe(seg.Edge, seg.StartTime, seg.Speed)
+	}
+}
+
+// IsochroneRequest parámetros para el cálculo de zonas alcanzables
+type IsochroneRequest struct {
+	Center  models.Location
+	Minutes []int  // tiempos de viaje de cada zona
+	Profile string // perfil de viaje
+}
+
+// Isochrone calcula los polígonos de las zonas alcanzables desde un punto dentro de
+// cada tiempo de viaje, considerando el tráfico en vivo. Las zonas se ordenan de la
+// mayor a la menor para que las menores queden encima al dibujarlas
+func (rs *RoutingService) Isochrone(req IsochroneRequest) (*models.FeatureCollection, error) {
+	plan, err := rs.newPlan(RouteRequest{Profile: req.Profile})
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := append([]int(nil), req.Minutes...)
+	if len(minutes) == 0 {
+		minutes = append(minutes, DefaultIsochroneMinutes...)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(minutes)))
+
+	limits := make([]float64, len(minutes))
+	for i, m := range minutes {
+		if m <= 0 || m > MaxIsochroneMinutes {
+			return nil, fmt.Errorf("tiempo de isócrona inválido: %d (máximo %d minutos)", m, MaxIsochroneMinutes)
+		}
+		limits[i] = float64(m) * 60
+	}
+
+	contours, err := rs.router.Isochrone(req.Center, plan.weighting, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &models.FeatureCollection{Type: "FeatureCollection", Features: []models.Feature{}}
+	for i, contour := range contours {
+		if contour == nil {
+			continue
+		}
+		collection.Features = append(collection.Features, models.Feature{
+			Type:     "Feature",
+			Geometry: utils.Polygon(contour),
+			Properties: map[string]interface{}{
+				"minutes": minutes[i],
+				"profile": plan.profile.Name,
+				"center":  []float64{req.Center.Lng, req.Center.Lat},
+			},
+		})
+	}
+	return collection, nil
+}
+
+// calculateMultiStop calcula una ruta que pasa por todas las paradas intermedias,
+// opcionalmente reordenadas para minimizar la duración total
+func (rs *RoutingService) calculateMultiStop(req RouteRequest, plan *routePlan) (*models.Route, error) {
+	stops := make([]models.Location, 0, len(req.Waypoints)+2)
+	stops = append(stops, req.From)
+	stops = append(stops, req.Waypoints...)
+	stops = append(stops, req.To)
+
+	if req.Optimize && len(req.Waypoints) > 1 {
+		// Una búsqueda por parada hacia todas las demás
+		costs := make([][]float64, len(stops))
+		for i := range stops {
+			costs[i] = make([]float64, len(stops))
+			cells, err := rs.router.OneToMany(stops[i], stops, plan.weighting)
+			if err != nil && !errors.Is(err, routing.ErrNoSnap) {
+				return nil, err
+			}
+			for j := range stops {
+				switch {
+				case i == j:
+				case j < len(cells) && cells[j].Reachable:
+					costs[i][j] = cells[j].Duration
+				default:
+					costs[i][j] = math.Inf(1)
+				}
+			}
+		}
+
+		ordered := make([]models.Location, len(stops))
+		for i, idx := range routing.OptimizeOrder(costs) {
+			ordered[i] = stops[idx]
+		}
+		stops = ordered
+	}
+
+	// Cada tramo sale a la hora en que se llega a su parada inicial
+	legs := make([]*routing.Path, 0, len(stops)-1)
+	elapsed := 0.0
+	for i := 0; i+1 < len(stops); i++ {
+		depart := plan.depart.Add(time.Duration(elapsed * float64(time.Second)))
+		path, err := rs.router.RouteWith(stops[i], stops[i+1], plan.at(rs.router, rs.history, depart), nil)
+		if err != nil {
+			return nil, fmt.Errorf("tramo %d: %w", i+1, err)
+		}
+		legs = append(legs, path)
+		elapsed += path.Duration
+	}
+
+	return rs.buildRoute(stops, legs, plan), nil
+}
+
+// buildRoute une los caminos entre paradas consecutivas en una ruta con resumen de
+// congestión e instrucciones paso a paso
+func (rs *RoutingService) buildRoute(stops []models.Location, legs []*routing.Path, plan *routePlan) *models.Route {
+	graph := rs.router.Graph()
+	congestion := &models.CongestionSummary{}
+	route := &models.Route{
+		From:       stops[0],
+		To:         stops[len(stops)-1],
+		Profile:    plan.profile.Name,
+		Congestion: congestion,
+	}
+	duration, freeFlow := 0.0, 0.0
+
+	for i, path := range legs {
+		for _, seg := range path.Segments {
+			speed := plan.profile.EdgeSpeed(&graph.Edges[seg.Edge])
+			segFreeFlow := seg.Distance / speed * 3600
+			freeFlow += segFreeFlow
+
+			ratio := 1.0
+			if seg.Duration > 0 {
+				ratio = segFreeFlow / seg.Duration
+			}
+			switch congestionLevel(ratio) {
+			case "low":
+				congestion.Low += seg.Distance
+			case "medium":
+				congestion.Medium += seg.Distance
+			default:
+				congestion.High += seg.Distance
+			}
+		}
+
+		points := path.Points
+		if i > 0 && len(points) > 0 {
+			points = points[1:] // evitar duplicar el punto de la parada
+		}
+		route.Points = append(route.Points, points...)
+		route.Distance += path.Distance
+		duration += path.Duration
+
+		steps := rs.router.Instructions(path, plan.lang)
+		if i < len(legs)-1 && len(steps) > 0 {
+			// La llegada de un tramo intermedio es una parada
+			last := &steps[len(steps)-1]
+			last.Maneuver = routing.ManeuverWaypoint
+			last.Instruction = routing.Localize(*last, plan.lang)
+		}
+		route.Steps = append(route.Steps, steps...)
+
+		if len(legs) > 1 {
+			route.Legs = append(route.Legs, models.RouteLeg{
+				From:     stops[i],
+				To:       stops[i+1],
+				Distance: path.Distance,
+				Duration: durationMinutes(path.Duration),
+			})
+		}
+	}
+
+	if len(stops) > 2 {
+		route.Waypoints = stops[1 : len(stops)-1]
+	}
+	route.Duration = durationMinutes(duration)
+	route.DepartAt = plan.depart
+	route.ArriveAt = plan.depart.Add(time.Duration(duration * float64(time.Second)))
+	route.Delay = int(math.Round((duration - freeFlow) / 60))
+	congestion.Level = "low"
+	if duration > 0 {
+		congestion.Level = congestionLevel(freeFlow / duration)
+	}
+
+	return route
+}
+
+// congestionLevel clasifica la relación entre velocidad actual y de flujo libre
+func congestionLevel(ratio float64) string {
+	if ratio >= 0.75 {
+		return "low"
+	} else if ratio >= 0.5 {
+		return "medium"
+	}
+	return "high"
+}
+
+// currentTraffic construye las condiciones de tráfico a partir de los datos en vivo
+// y de los reportes activos de accidentes, congestión, inundaciones y cierres
+func (rs *RoutingService) currentTraffic() *routing.Traffic {
+	graph := rs.router.Graph()
+	traffic := routing.NewTraffic()
+	if graph.Empty() {
+		return traffic
+	}
+
+	for _, data := range rs.storage.GetTrafficData() {
+		if time.Since(data.Timestamp) > trafficMaxAge {
+			continue
+		}
+		for _, snap := range graph.Nearby(data.Lat, data.Lng, trafficRadius) {
+			traffic.SetSpeed(snap.Edge, data.Speed, snap.Distance)
+		}
+	}
+
+	for _, report := range rs.storage.GetRecentReports() {
+		factor, ok := incidentFactor(report)
+		if !ok {
+			continue
+		}
+		// Un reporte con poca confianza reduce menos la velocidad
+		factor = 1 - (1-factor)*report.Confidence
+		for _, snap := range graph.Nearby(report.Lat, report.Lng, incidentRadius) {
+			traffic.ApplyFactor(snap.Edge, factor)
+		}
+	}
+
+	return traffic
+}
+
+// durationMinutes convierte segundos a minutos redondeando hacia arriba
+func durationMinutes(seconds float64) int {
+	return int(math.Ceil(seconds / 60))
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// SessionCookie cookie con el token de sesión que se entrega al registrar un usuario
+	SessionCookie = "gowaze_session"
+	// SessionTTL vigencia de una sesión desde que se abre
+	SessionTTL = 24 * time.Hour
+	// MaxSessionsPerClient sesiones que puede abrir una misma dirección en SessionIssueWindow
+	MaxSessionsPerClient = 5
+	// SessionIssueWindow ventana en que se cuentan las sesiones abiertas por dirección
+	SessionIssueWindow = time.Hour
+	// sessionCleanupInterval frecuencia del borrado de sesiones vencidas
+	sessionCleanupInterval = time.Hour
+)
+
+var (
+	// ErrNoSession indica una acción que requiere identificar al usuario sin una sesión vigente
+	ErrNoSession = errors.New("se requiere una sesión: registre un usuario primero")
+	// ErrSessionLimit indica que una dirección ya abrió MaxSessionsPerClient sesiones en
+	// la ventana actual
+	ErrSessionLimit = errors.New("demasiadas sesiones abiertas desde esta dirección: intente más tarde")
+)
+
+// session usuario de una sesión y su vencimiento
+type session struct {
+	userID    int
+	expiresAt time.Time
+}
+
+// issuance sesiones abiertas por una dirección desde el inicio de su ventana
+type issuance struct {
+	count int
+	since time.Time
+}
+
+// SessionStore asocia tokens aleatorios emitidos por el servidor con el usuario que
+// los obtuvo, para identificar a quien vota sin confiar en un ID enviado por el cliente.
+// Registrar un usuario no exige nada que no pueda repetirse, así que cada dirección
+// abre como mucho MaxSessionsPerClient sesiones por SessionIssueWindow. Esto limita,
+// pero no impide, votar varias veces: quien disponga de muchas direcciones obtiene
+// una sesión con cada una, y los clientes detrás de un mismo proxy comparten el límite
+type SessionStore struct {
+	sessions map[string]session
+	issued   map[string]issuance
+	mu       sync.RWMutex
+}
+
+// NewSessionStore crea un almacén de sesiones vacío
+func NewSessionStore() *SessionStore {
+	return &SessionStore{
+		sessions: make(map[string]session),
+		issued:   make(map[string]issuance),
+	}
+}
+
+// Reserve cuenta una sesión nueva para la dirección de un cliente, antes de registrar
+// al usuario que la recibirá. Retorna ErrSessionLimit si la dirección agotó su cupo
+func (ss *SessionStore) Reserve(client string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	now := time.Now()
+	issued := ss.issued[client]
+	if now.Sub(issued.since) >= SessionIssueWindow {
+		issued = issuance{since: now}
+	}
+	if issued.count >= MaxSessionsPerClient {
+		return ErrSessionLimit
+	}
+	issued.count++
+	ss.issued[client] = issued
+	return nil
+}
+
+// Create abre una sesión para un usuario y retorna su token
+func (ss *SessionStore) Create(userID int) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.sessions[token] = session{userID: userID, expiresAt: time.Now().Add(SessionTTL)}
+	return token, nil
+}
+
+// User retorna el usuario de una sesión vigente
+func (ss *SessionStore) User(token string) (int, bool) {
+	if token == "" {
+		return 0, false
+	}
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	s, ok := ss.sessions[token]
+	if !ok || time.Now().After(s.expiresAt) {
+		return 0, false
+	}
+	return s.userID, true
+}
+
+// StartCleanup borra periódicamente las sesiones vencidas
+func (ss *SessionStore) StartCleanup() {
+	ticker := time.NewTicker(sessionCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ss.cleanup()
+	}
+}
+
+func (ss *SessionStore) cleanup() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	now := time.Now()
+	for token, s := range ss.sessions {
+		if now.After(s.expiresAt) {
+			delete(ss.sessions, token)
+		}
+	}
+	for client, issued := range ss.issued {
+		if now.Sub(issued.since) >= SessionIssueWindow {
+			delete(ss.issued, client)
+		}
+	}
+}
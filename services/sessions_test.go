@@ -0,0 +1,30 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionReserve(t *testing.T) {
+	ss := NewSessionStore()
+	for i := 0; i < MaxSessionsPerClient; i++ {
+		if err := ss.Reserve("10.0.0.1"); err != nil {
+			t.Fatalf("sesión %d: %v", i+1, err)
+		}
+	}
+	if err := ss.Reserve("10.0.0.1"); !errors.Is(err, ErrSessionLimit) {
+		t.Errorf("sesión por encima del límite retornó %v, esperado %v", err, ErrSessionLimit)
+	}
+	if err := ss.Reserve("10.0.0.2"); err != nil {
+		t.Errorf("otra dirección retornó %v", err)
+	}
+
+	// Al terminar la ventana la dirección recupera su cupo
+	issued := ss.issued["10.0.0.1"]
+	issued.since = time.Now().Add(-SessionIssueWindow)
+	ss.issued["10.0.0.1"] = issued
+	if err := ss.Reserve("10.0.0.1"); err != nil {
+		t.Errorf("tras la ventana retornó %v", err)
+	}
+}
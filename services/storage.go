@@ -1,35 +1,146 @@
 package services
 
 import (
+	"errors"
 	"gowaze/models"
+	"gowaze/routing"
+	"gowaze/utils"
 	"log"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
 
-// Storage maneja el almacenamiento en memoria
-type Storage struct {
+// Votos sobre un reporte
+const (
+	VoteUp   = 1  // "sigue ahí"
+	VoteDown = -1 // "ya no está"
+)
+
+var (
+	// ErrReportNotFound indica que el reporte no existe o ya no está activo
+	ErrReportNotFound = errors.New("reporte no encontrado")
+	// ErrAlreadyVoted indica que el usuario ya emitió ese mismo voto sobre el reporte
+	ErrAlreadyVoted = errors.New("ya votaste este reporte")
+)
+
+// Storage almacena usuarios, reportes, datos de tráfico, viajes y el historial de
+// velocidades aprendido de ellos. MemoryStorage
+// los mantiene solo en memoria; FileStorage además los persiste en disco. Si una
+// modificación no se puede persistir retorna el error y el estado no cambia
+type Storage interface {
+	CreateUser(username string, lat, lng float64) (*models.User, error)
+	CreateReport(draft *models.Report) (*models.Report, bool, error)
+	GetRecentReports() []*models.Report
+	VoteReport(reportID, userID, vote int) (*models.Report, bool, error)
+	RemoveReport(id int) (*models.Report, error)
+	GetStats() (int, int, int)
+	UpdateTrafficData(key string, data *models.TrafficData) error
+	GetTrafficData() map[string]*models.TrafficData
+	ReportsInBox(box utils.BBox) []*models.Report
+	ReportsWithin(lat, lng, radius float64) []*models.Report
+	NearestReports(lat, lng float64, k int) []*models.Report
+	TrafficInBox(box utils.BBox) []*models.TrafficData
+	TrafficWithin(lat, lng, radius float64) []*models.TrafficData
+	NearestTraffic(lat, lng float64, k int) []*models.TrafficData
+	SaveTrip(trip *models.Trip) (*models.Trip, error)
+	GetTrip(id int) (*models.Trip, bool)
+	GetTrips() []*models.Trip
+	SaveSpeedHistory(data *routing.HistoryData) error
+	LoadSpeedHistory() (*routing.HistoryData, error)
+	InitSampleData() error
+	StartCleanup()
+	Close() error
+}
+
+// MemoryStorage maneja el almacenamiento en memoria
+type MemoryStorage struct {
 	Users        map[int]*models.User
 	Reports      map[int]*models.Report
 	TrafficData  map[string]*models.TrafficData
+	Trips        map[int]*models.Trip
+	Voters       map[int]map[int]int // voto de cada usuario por reporte
 	NextUserID   int
 	NextReportID int
+	NextTripID   int
+	reportIndex  *utils.PointIndex         // ubicación de los reportes por ID
+	trafficIndex *utils.PointIndex         // ubicación de los datos de tráfico por clave
+	speeds       *routing.HistoryData      // historial de velocidades guardado
+	journal      func(storageRecord) error // persiste cada modificación antes de aplicarla
 	mu           sync.RWMutex
 }
 
-// NewStorage crea una nueva instancia de Storage
-func NewStorage() *Storage {
-	return &Storage{
+// Operaciones sobre el almacenamiento
+const (
+	recordUser    = "user"
+	recordReport  = "report"
+	recordTraffic = "traffic"
+	recordTrip    = "trip"
+	recordVote    = "vote"
+	recordRemove  = "report_removed"
+	recordExpire  = "expired"
+)
+
+// Vigencia de los datos que borra la limpieza automática
+const (
+	// userTTL inactividad tras la que se borra un usuario; no menor que SessionTTL para
+	// que el usuario de una sesión vigente siga existiendo
+	userTTL = SessionTTL
+	// tripTTL antigüedad tras la que se borra un viaje
+	tripTTL = 7 * 24 * time.Hour
+	// trafficTTL antigüedad tras la que se borran los datos de tráfico
+	trafficTTL = time.Hour
+)
+
+// storageRecord modificación del almacenamiento: el objeto completo resultante, de
+// modo que reaplicarla reemplaza la versión anterior y es idempotente. FileStorage
+// las registra en su WAL
+type storageRecord struct {
+	Op      string              `json:"op"`
+	Key     string              `json:"key,omitempty"`
+	ID      int                 `json:"id,omitempty"`      // reporte votado o retirado
+	UserID  int                 `json:"user_id,omitempty"` // autor de un voto o reporte, 0 si es anónimo
+	Vote    int                 `json:"vote,omitempty"`
+	Retired bool                `json:"retired,omitempty"` // el voto retiró el reporte
+	User    *models.User        `json:"user,omitempty"`
+	Report  *models.Report      `json:"report,omitempty"`
+	Traffic *models.TrafficData `json:"traffic,omitempty"`
+	Trip    *models.Trip        `json:"trip,omitempty"`
+	Expired *storageExpiry      `json:"expired,omitempty"`
+}
+
+// storageExpiry datos vencidos que borra una limpieza, registrados juntos para que no
+// reaparezcan al reaplicar el WAL
+type storageExpiry struct {
+	Users   []int    `json:"users,omitempty"`
+	Reports []int    `json:"reports,omitempty"`
+	Trips   []int    `json:"trips,omitempty"`
+	Traffic []string `json:"traffic,omitempty"`
+}
+
+func (e *storageExpiry) empty() bool {
+	return len(e.Users) == 0 && len(e.Reports) == 0 && len(e.Trips) == 0 && len(e.Traffic) == 0
+}
+
+// NewMemoryStorage crea una nueva instancia de MemoryStorage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
 		Users:        make(map[int]*models.User),
 		Reports:      make(map[int]*models.Report),
 		TrafficData:  make(map[string]*models.TrafficData),
+		Trips:        make(map[int]*models.Trip),
+		Voters:       make(map[int]map[int]int),
 		NextUserID:   1,
 		NextReportID: 1,
+		NextTripID:   1,
+		reportIndex:  utils.NewPointIndex(),
+		trafficIndex: utils.NewPointIndex(),
 	}
 }
 
 // CreateUser crea o actualiza un usuario
-func (s *Storage) CreateUser(username string, lat, lng float64) *models.User {
+func (s *MemoryStorage) CreateUser(username string, lat, lng float64) (*models.User, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -40,49 +151,229 @@ func (s *Storage) CreateUser(username string, lat, lng float64) *models.User {
 		Lng:      lng,
 		LastSeen: time.Now(),
 	}
-	s.Users[s.NextUserID] = user
-	s.NextUserID++
-
-	return user
+	if err := s.commit(storageRecord{Op: recordUser, User: user}); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
-// CreateReport crea un nuevo reporte
-func (s *Storage) CreateReport(reportType string, lat, lng float64, description string, userID int) *models.Report {
+// CreateReport crea un nuevo reporte a partir de los datos de quien reporta (tipo,
+// subtipo, ubicación, descripción, usuario, gravedad, sentido, carriles y fotos) o, si
+// duplica un incidente vigente del mismo tipo cercano y reciente, lo agrupa en él.
+// UserID 0 indica un reporte anónimo. Indica si el reporte se agrupó
+func (s *MemoryStorage) CreateReport(draft *models.Report) (*models.Report, bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	now := time.Now()
+	if incident := s.duplicateOf(draft, now); incident != nil {
+		merged := s.merge(incident, draft, now)
+		if err := s.commit(storageRecord{Op: recordReport, Report: merged, UserID: draft.UserID}); err != nil {
+			return nil, false, err
+		}
+		return presentReport(merged, now), true, nil
+	}
+
 	report := &models.Report{
 		ID:          s.NextReportID,
-		Type:        reportType,
-		Lat:         lat,
-		Lng:         lng,
-		Description: description,
-		UserID:      userID,
-		CreatedAt:   time.Now(),
+		Type:        draft.Type,
+		Subtype:     draft.Subtype,
+		Severity:    draft.Severity,
+		Direction:   draft.Direction,
+		Lanes:       append([]int(nil), draft.Lanes...),
+		Photos:      append([]models.Photo(nil), draft.Photos...),
+		Lat:         draft.Lat,
+		Lng:         draft.Lng,
+		Description: draft.Description,
+		UserID:      draft.UserID,
+		CreatedAt:   now,
 		Votes:       1,
+		Upvotes:     1,
+		ConfirmedAt: now,
 	}
-	s.Reports[s.NextReportID] = report
-	s.NextReportID++
+	if err := s.commit(storageRecord{Op: recordReport, Report: report, UserID: report.UserID}); err != nil {
+		return nil, false, err
+	}
+	return presentReport(report, now), false, nil
+}
 
-	return report
+// duplicateOf busca el incidente vigente más cercano del mismo tipo (y subtipo, si
+// ambos lo indican) dentro de mergeRadius y con actividad en los últimos mergeWindow
+func (s *MemoryStorage) duplicateOf(draft *models.Report, now time.Time) *models.Report {
+	for _, hit := range s.reportIndex.Within(draft.Lat, draft.Lng, mergeRadius) {
+		id, _ := strconv.Atoi(hit.Key)
+		report, ok := s.Reports[id]
+		if !ok || report.Type != draft.Type || !reportActive(report, now) {
+			continue
+		}
+		if report.Subtype != "" && draft.Subtype != "" && report.Subtype != draft.Subtype {
+			continue
+		}
+		if now.Sub(report.ConfirmedAt) <= mergeWindow {
+			return report
+		}
+	}
+	return nil
+}
+
+// merge agrupa un nuevo reporte en un incidente: le asigna su propio ID para
+// auditoría, desplaza la ubicación al promedio de los reportes, suma su descripción,
+// detalles, gravedad y fotos, y lo cuenta como confirmación del usuario. Retorna una
+// copia sin modificar el incidente
+func (s *MemoryStorage) merge(incident, draft *models.Report, now time.Time) *models.Report {
+	merged := *incident
+	merged.MergedIDs = append(append([]int(nil), incident.MergedIDs...), s.NextReportID)
+
+	count := float64(len(merged.MergedIDs) + 1)
+	merged.Lat += (draft.Lat - merged.Lat) / count
+	merged.Lng += (draft.Lng - merged.Lng) / count
+
+	if merged.Subtype == "" {
+		merged.Subtype = draft.Subtype
+	}
+	if draft.Severity > merged.Severity {
+		merged.Severity = draft.Severity
+	}
+	if merged.Direction == "" {
+		merged.Direction = draft.Direction
+	}
+	merged.Lanes = mergeLanes(incident.Lanes, draft.Lanes)
+	if len(draft.Photos) > 0 {
+		merged.Photos = append(append([]models.Photo(nil), incident.Photos...), draft.Photos...)
+	}
+
+	if draft.Description != "" {
+		merged.Descriptions = append([]string(nil), incident.Descriptions...)
+		if len(merged.Descriptions) == 0 && merged.Description != "" {
+			merged.Descriptions = append(merged.Descriptions, merged.Description)
+		}
+		if !containsString(merged.Descriptions, draft.Description) {
+			merged.Descriptions = append(merged.Descriptions, draft.Description)
+		}
+		if merged.Description == "" {
+			merged.Description = draft.Description
+		}
+	}
+
+	// El autor confirma el incidente salvo que ya lo hubiera hecho: repetir el reporte
+	// no lo mantiene vigente frente a los descartes. Un reporte anónimo (UserID 0) no se
+	// puede atribuir y cuenta siempre como una confirmación
+	switch s.Voters[merged.ID][draft.UserID] {
+	case VoteDown:
+		merged.Downvotes--
+		fallthrough
+	case 0:
+		merged.Upvotes++
+		merged.Dismissals = 0
+		merged.ConfirmedAt = now
+	}
+	merged.Votes = merged.Upvotes - merged.Downvotes
+	return &merged
+}
+
+// mergeLanes une dos listas ordenadas de carriles afectados
+func mergeLanes(a, b []int) []int {
+	lanes := append(append([]int(nil), a...), b...)
+	sort.Ints(lanes)
+	merged := lanes[:0]
+	for i, lane := range lanes {
+		if i == 0 || lane != lanes[i-1] {
+			merged = append(merged, lane)
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
 }
 
-// GetRecentReports obtiene reportes de las últimas 24 horas
-func (s *Storage) GetRecentReports() []*models.Report {
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRecentReports obtiene los reportes vigentes según la vida de su tipo, con su confianza
+func (s *MemoryStorage) GetRecentReports() []*models.Report {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now()
 	reports := make([]*models.Report, 0, len(s.Reports))
 	for _, report := range s.Reports {
-		if time.Since(report.CreatedAt) < 24*time.Hour {
-			reports = append(reports, report)
+		if reportActive(report, now) {
+			reports = append(reports, presentReport(report, now))
 		}
 	}
 	return reports
 }
 
+// VoteReport registra el voto "sigue ahí" (VoteUp) o "ya no está" (VoteDown) de un
+// usuario sobre un reporte activo. Un usuario puede cambiar su voto pero no repetirlo.
+// El reporte se reemplaza por una copia actualizada para no modificar los que ya se
+// entregaron, o se retira si acumula RetireDismissals votos negativos desde su última
+// confirmación; el voto y el retiro son una sola modificación. Retorna el reporte
+// actualizado e indica si fue retirado
+func (s *MemoryStorage) VoteReport(reportID, userID, vote int) (*models.Report, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, ok := s.Reports[reportID]
+	now := time.Now()
+	if !ok || !reportActive(report, now) {
+		return nil, false, ErrReportNotFound
+	}
+	previous := s.Voters[reportID][userID]
+	if previous == vote {
+		return nil, false, ErrAlreadyVoted
+	}
+
+	updated := *report
+	switch previous {
+	case VoteUp:
+		updated.Upvotes--
+	case VoteDown:
+		updated.Downvotes--
+	}
+	switch vote {
+	case VoteUp:
+		updated.Upvotes++
+		updated.Dismissals = 0
+		updated.ConfirmedAt = now
+	case VoteDown:
+		updated.Downvotes++
+		updated.Dismissals++
+	}
+	updated.Votes = updated.Upvotes - updated.Downvotes
+
+	retired := updated.Dismissals >= RetireDismissals
+	record := storageRecord{Op: recordVote, ID: reportID, Report: &updated, UserID: userID, Vote: vote, Retired: retired}
+	if err := s.commit(record); err != nil {
+		return nil, false, err
+	}
+	return presentReport(&updated, now), retired, nil
+}
+
+// RemoveReport retira un reporte y retorna su última versión
+func (s *MemoryStorage) RemoveReport(id int) (*models.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, ok := s.Reports[id]
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+	if err := s.commit(storageRecord{Op: recordRemove, ID: id}); err != nil {
+		return nil, err
+	}
+	return presentReport(report, time.Now()), nil
+}
+
 // GetStats obtiene estadísticas generales
-func (s *Storage) GetStats() (int, int, int) {
+func (s *MemoryStorage) GetStats() (int, int, int) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -94,14 +385,14 @@ func (s *Storage) GetStats() (int, int, int) {
 }
 
 // UpdateTrafficData actualiza datos de tráfico
-func (s *Storage) UpdateTrafficData(key string, data *models.TrafficData) {
+func (s *MemoryStorage) UpdateTrafficData(key string, data *models.TrafficData) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.TrafficData[key] = data
+	return s.commit(storageRecord{Op: recordTraffic, Key: key, Traffic: data})
 }
 
 // GetTrafficData obtiene todos los datos de tráfico
-func (s *Storage) GetTrafficData() map[string]*models.TrafficData {
+func (s *MemoryStorage) GetTrafficData() map[string]*models.TrafficData {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -113,52 +404,303 @@ func (s *Storage) GetTrafficData() map[string]*models.TrafficData {
 	return data
 }
 
-// InitSampleData inicializa datos de ejemplo
-func (s *Storage) InitSampleData() {
+// reportKey clave de un reporte en el índice espacial
+func reportKey(id int) string {
+	return strconv.Itoa(id)
+}
+
+// reportsFor convierte resultados del índice en reportes activos, en el mismo orden
+func (s *MemoryStorage) reportsFor(hits []utils.SpatialHit) []*models.Report {
+	now := time.Now()
+	reports := make([]*models.Report, 0, len(hits))
+	for _, hit := range hits {
+		id, _ := strconv.Atoi(hit.Key)
+		if report, ok := s.Reports[id]; ok && reportActive(report, now) {
+			reports = append(reports, presentReport(report, now))
+		}
+	}
+	return reports
+}
+
+// trafficFor convierte resultados del índice en datos de tráfico, en el mismo orden
+func (s *MemoryStorage) trafficFor(hits []utils.SpatialHit) []*models.TrafficData {
+	data := make([]*models.TrafficData, 0, len(hits))
+	for _, hit := range hits {
+		if traffic, ok := s.TrafficData[hit.Key]; ok {
+			data = append(data, traffic)
+		}
+	}
+	return data
+}
+
+// ReportsInBox obtiene los reportes activos dentro de un rectángulo
+func (s *MemoryStorage) ReportsInBox(box utils.BBox) []*models.Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reportsFor(s.reportIndex.InBox(box))
+}
+
+// ReportsWithin obtiene los reportes activos a menos de radius km, del más cercano al más lejano
+func (s *MemoryStorage) ReportsWithin(lat, lng, radius float64) []*models.Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reportsFor(s.reportIndex.Within(lat, lng, radius))
+}
+
+// NearestReports obtiene los k reportes activos más cercanos a un punto
+func (s *MemoryStorage) NearestReports(lat, lng float64, k int) []*models.Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Los reportes vencidos siguen indexados hasta la limpieza: pedir de más y recortar
+	reports := s.reportsFor(s.reportIndex.Nearest(lat, lng, k))
+	for extra := k; len(reports) < k && extra < s.reportIndex.Len(); extra *= 2 {
+		reports = s.reportsFor(s.reportIndex.Nearest(lat, lng, k+extra))
+	}
+	if len(reports) > k {
+		reports = reports[:k]
+	}
+	return reports
+}
+
+// TrafficInBox obtiene los datos de tráfico dentro de un rectángulo
+func (s *MemoryStorage) TrafficInBox(box utils.BBox) []*models.TrafficData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.trafficFor(s.trafficIndex.InBox(box))
+}
+
+// TrafficWithin obtiene los datos de tráfico a menos de radius km, del más cercano al más lejano
+func (s *MemoryStorage) TrafficWithin(lat, lng, radius float64) []*models.TrafficData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.trafficFor(s.trafficIndex.Within(lat, lng, radius))
+}
+
+// NearestTraffic obtiene los k datos de tráfico más cercanos a un punto
+func (s *MemoryStorage) NearestTraffic(lat, lng float64, k int) []*models.TrafficData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.trafficFor(s.trafficIndex.Nearest(lat, lng, k))
+}
+
+// SaveTrip guarda una copia de un viaje registrado con su ID asignado
+func (s *MemoryStorage) SaveTrip(trip *models.Trip) (*models.Trip, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := *trip
+	saved.ID = s.NextTripID
+	if err := s.commit(storageRecord{Op: recordTrip, Trip: &saved}); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// GetTrip obtiene un viaje por su ID
+func (s *MemoryStorage) GetTrip(id int) (*models.Trip, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trip, ok := s.Trips[id]
+	return trip, ok
+}
+
+// GetTrips obtiene todos los viajes registrados, del más reciente al más antiguo
+func (s *MemoryStorage) GetTrips() []*models.Trip {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trips := make([]*models.Trip, 0, len(s.Trips))
+	for _, trip := range s.Trips {
+		trips = append(trips, trip)
+	}
+	sort.Slice(trips, func(i, j int) bool {
+		return trips[i].StartedAt.After(trips[j].StartedAt)
+	})
+	return trips
+}
+
+// InitSampleData inicializa datos de ejemplo si todavía no hay reportes
+func (s *MemoryStorage) InitSampleData() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if len(s.Reports) > 0 {
+		return nil
+	}
+
 	// Reportes de ejemplo
-	s.Reports[1] = &models.Report{
-		ID:          1,
-		Type:        "traffic",
-		Lat:         14.0818,
-		Lng:         -87.2068,
-		Description: "Tráfico pesado en el centro de San Pedro Sula",
-		UserID:      1,
-		CreatedAt:   time.Now().Add(-10 * time.Minute),
-		Votes:       5,
-	}
-
-	s.Reports[2] = &models.Report{
-		ID:          2,
-		Type:        "police",
-		Lat:         14.0900,
-		Lng:         -87.2100,
-		Description: "Control policial en Bulevar del Norte",
-		UserID:      1,
-		CreatedAt:   time.Now().Add(-5 * time.Minute),
-		Votes:       3,
-	}
-
-	s.Reports[3] = &models.Report{
-		ID:          3,
-		Type:        "accident",
-		Lat:         14.0750,
-		Lng:         -87.2200,
-		Description: "Accidente menor en intersección",
-		UserID:      1,
-		CreatedAt:   time.Now().Add(-15 * time.Minute),
-		Votes:       7,
-	}
-
-	s.NextReportID = 4
+	samples := []*models.Report{
+		{
+			ID:          1,
+			Type:        "traffic",
+			Lat:         14.0818,
+			Lng:         -87.2068,
+			Description: "Tráfico pesado en el centro de San Pedro Sula",
+			UserID:      1,
+			CreatedAt:   time.Now().Add(-10 * time.Minute),
+			Votes:       5,
+		},
+		{
+			ID:          2,
+			Type:        "police",
+			Lat:         14.0900,
+			Lng:         -87.2100,
+			Description: "Control policial en Bulevar del Norte",
+			UserID:      1,
+			CreatedAt:   time.Now().Add(-5 * time.Minute),
+			Votes:       3,
+		},
+		{
+			ID:          3,
+			Type:        "accident",
+			Subtype:     "minor",
+			Lat:         14.0750,
+			Lng:         -87.2200,
+			Description: "Accidente menor en intersección",
+			UserID:      1,
+			CreatedAt:   time.Now().Add(-15 * time.Minute),
+			Votes:       7,
+		},
+	}
+
+	for _, report := range samples {
+		report.Upvotes = report.Votes
+		report.ConfirmedAt = report.CreatedAt
+		report.Severity = defaultSeverity(report.Type, report.Subtype)
+		if err := s.commit(storageRecord{Op: recordReport, Report: report, UserID: report.UserID}); err != nil {
+			return err
+		}
+	}
 
 	log.Println("✅ Datos de ejemplo inicializados")
+	return nil
+}
+
+// SaveSpeedHistory guarda el historial de velocidades
+func (s *MemoryStorage) SaveSpeedHistory(data *routing.HistoryData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.speeds = data
+	return nil
+}
+
+// LoadSpeedHistory obtiene el último historial de velocidades guardado, nil si no hay
+func (s *MemoryStorage) LoadSpeedHistory() (*routing.HistoryData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.speeds, nil
+}
+
+// Close libera los recursos del almacenamiento
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// commit persiste una modificación, si el almacenamiento tiene dónde, y solo entonces
+// la aplica en memoria. Requiere s.mu
+func (s *MemoryStorage) commit(record storageRecord) error {
+	if s.journal != nil {
+		if err := s.journal(record); err != nil {
+			return err
+		}
+	}
+	s.apply(record)
+	return nil
+}
+
+// apply aplica una modificación sobre el estado en memoria. Requiere s.mu
+func (s *MemoryStorage) apply(record storageRecord) {
+	switch {
+	case record.Op == recordUser && record.User != nil:
+		s.putUser(record.User)
+	case record.Op == recordReport && record.Report != nil:
+		s.putReport(record.Report)
+		if record.UserID != 0 {
+			s.putVote(record.Report.ID, record.UserID, VoteUp)
+		}
+	case record.Op == recordVote && record.Retired:
+		s.removeReport(record.ID)
+	case record.Op == recordVote && record.Report != nil:
+		s.putReport(record.Report)
+		s.putVote(record.Report.ID, record.UserID, record.Vote)
+	case record.Op == recordRemove:
+		s.removeReport(record.ID)
+	case record.Op == recordTraffic && record.Traffic != nil:
+		s.putTraffic(record.Key, record.Traffic)
+	case record.Op == recordTrip && record.Trip != nil:
+		s.putTrip(record.Trip)
+	case record.Op == recordExpire && record.Expired != nil:
+		for _, id := range record.Expired.Users {
+			delete(s.Users, id)
+		}
+		for _, id := range record.Expired.Reports {
+			s.removeReport(id)
+		}
+		for _, id := range record.Expired.Trips {
+			delete(s.Trips, id)
+		}
+		for _, key := range record.Expired.Traffic {
+			delete(s.TrafficData, key)
+			s.trafficIndex.Remove(key)
+		}
+	}
+}
+
+// putUser agrega o reemplaza un usuario conservando su ID. Requiere s.mu
+func (s *MemoryStorage) putUser(user *models.User) {
+	s.Users[user.ID] = user
+	if user.ID >= s.NextUserID {
+		s.NextUserID = user.ID + 1
+	}
+}
+
+// putReport agrega o reemplaza un reporte conservando su ID. Requiere s.mu
+func (s *MemoryStorage) putReport(report *models.Report) {
+	s.Reports[report.ID] = report
+	s.reportIndex.Insert(reportKey(report.ID), report.Lat, report.Lng)
+	for _, id := range append([]int{report.ID}, report.MergedIDs...) {
+		if id >= s.NextReportID {
+			s.NextReportID = id + 1
+		}
+	}
+}
+
+// removeReport elimina un reporte con sus votos. Requiere s.mu
+func (s *MemoryStorage) removeReport(id int) {
+	delete(s.Reports, id)
+	delete(s.Voters, id)
+	s.reportIndex.Remove(reportKey(id))
+}
+
+// putVote registra el voto de un usuario sin modificar los contadores del reporte.
+// Requiere s.mu
+func (s *MemoryStorage) putVote(reportID, userID, vote int) {
+	voters, ok := s.Voters[reportID]
+	if !ok {
+		voters = make(map[int]int)
+		s.Voters[reportID] = voters
+	}
+	voters[userID] = vote
+}
+
+// putTraffic agrega o reemplaza datos de tráfico. Requiere s.mu
+func (s *MemoryStorage) putTraffic(key string, data *models.TrafficData) {
+	s.TrafficData[key] = data
+	s.trafficIndex.Insert(key, data.Lat, data.Lng)
+}
+
+// putTrip agrega o reemplaza un viaje conservando su ID. Requiere s.mu
+func (s *MemoryStorage) putTrip(trip *models.Trip) {
+	s.Trips[trip.ID] = trip
+	if trip.ID >= s.NextTripID {
+		s.NextTripID = trip.ID + 1
+	}
 }
 
 // StartCleanup inicia la limpieza automática de datos antiguos
-func (s *Storage) StartCleanup() {
+func (s *MemoryStorage) StartCleanup() {
 	ticker := time.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
 
@@ -167,32 +709,49 @@ func (s *Storage) StartCleanup() {
 	}
 }
 
-// cleanup limpia datos antiguos
-func (s *Storage) cleanup() {
+// cleanup limpia datos antiguos. Los borrados pasan por commit como cualquier otra
+// modificación, de modo que FileStorage los registra en el WAL
+func (s *MemoryStorage) cleanup() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	now := time.Now()
+	expired := &storageExpiry{}
 
-	// Limpiar usuarios inactivos (más de 1 hora)
+	// Limpiar usuarios inactivos
 	for id, user := range s.Users {
-		if time.Since(user.LastSeen) > time.Hour {
-			delete(s.Users, id)
+		if now.Sub(user.LastSeen) > userTTL {
+			expired.Users = append(expired.Users, id)
 		}
 	}
 
-	// Limpiar reportes antiguos (más de 24 horas)
+	// Limpiar reportes vencidos según la vida de su tipo
 	for id, report := range s.Reports {
-		if time.Since(report.CreatedAt) > 24*time.Hour {
-			delete(s.Reports, id)
+		if !reportActive(report, now) {
+			expired.Reports = append(expired.Reports, id)
+		}
+	}
+
+	// Limpiar viajes antiguos
+	for id, trip := range s.Trips {
+		if now.Sub(trip.EndedAt) > tripTTL {
+			expired.Trips = append(expired.Trips, id)
 		}
 	}
 
-	// Limpiar datos de tráfico antiguos (más de 1 hora)
+	// Limpiar datos de tráfico antiguos
 	for key, traffic := range s.TrafficData {
-		if time.Since(traffic.Timestamp) > time.Hour {
-			delete(s.TrafficData, key)
+		if now.Sub(traffic.Timestamp) > trafficTTL {
+			expired.Traffic = append(expired.Traffic, key)
+		}
+	}
+
+	if !expired.empty() {
+		if err := s.commit(storageRecord{Op: recordExpire, Expired: expired}); err != nil {
+			log.Printf("Error borrando datos vencidos: %v", err)
+			return
 		}
 	}
 
 	log.Printf("🧹 Limpieza automática completada. Users: %d, Reports: %d, Traffic: %d",
 		len(s.Users), len(s.Reports), len(s.TrafficData))
-}
\ No newline at end of file
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"gowaze/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCreateReportMerging(t *testing.T) {
+	tests := []struct {
+		name        string
+		first       models.Report
+		idle        time.Duration // tiempo desde la última confirmación del primero
+		second      models.Report
+		wantMerged  bool
+		wantUpvotes int
+	}{
+		{
+			name:        "mismo tipo cerca y reciente",
+			first:       models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: 1},
+			second:      models.Report{Type: "accident", Lat: testLat + 0.00045, Lng: testLng, UserID: 2},
+			wantMerged:  true,
+			wantUpvotes: 2,
+		},
+		{
+			name:   "fuera del radio",
+			first:  models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: 1},
+			second: models.Report{Type: "accident", Lat: testLat + 0.0018, Lng: testLng, UserID: 2},
+		},
+		{
+			name:   "sin actividad reciente",
+			first:  models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: 1},
+			idle:   mergeWindow + time.Minute,
+			second: models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: 2},
+		},
+		{
+			name:   "distinto tipo",
+			first:  models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: 1},
+			second: models.Report{Type: "police", Lat: testLat, Lng: testLng, UserID: 2},
+		},
+		{
+			name:   "distinto subtipo",
+			first:  models.Report{Type: "hazard", Subtype: "pothole", Lat: testLat, Lng: testLng, UserID: 1},
+			second: models.Report{Type: "hazard", Subtype: "flooding", Lat: testLat, Lng: testLng, UserID: 2},
+		},
+		{
+			name:        "subtipo sin indicar",
+			first:       models.Report{Type: "hazard", Lat: testLat, Lng: testLng, UserID: 1},
+			second:      models.Report{Type: "hazard", Subtype: "pothole", Lat: testLat, Lng: testLng, UserID: 2},
+			wantMerged:  true,
+			wantUpvotes: 2,
+		},
+		{
+			name:        "el mismo autor no confirma dos veces",
+			first:       models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: 1},
+			second:      models.Report{Type: "accident", Lat: testLat, Lng: testLng, UserID: 1},
+			wantMerged:  true,
+			wantUpvotes: 1,
+		},
+		{
+			name:        "cada reporte anónimo confirma",
+			first:       models.Report{Type: "accident", Lat: testLat, Lng: testLng},
+			second:      models.Report{Type: "accident", Lat: testLat, Lng: testLng},
+			wantMerged:  true,
+			wantUpvotes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStorage()
+			first, _, err := s.CreateReport(&tt.first)
+			if err != nil {
+				t.Fatalf("CreateReport: %v", err)
+			}
+			s.Reports[first.ID].ConfirmedAt = time.Now().Add(-tt.idle)
+
+			second, merged, err := s.CreateReport(&tt.second)
+			if err != nil {
+				t.Fatalf("CreateReport: %v", err)
+			}
+			if merged != tt.wantMerged {
+				t.Fatalf("agrupado = %v, esperado %v", merged, tt.wantMerged)
+			}
+			if !merged {
+				if second.ID == first.ID || len(s.Reports) != 2 {
+					t.Errorf("el reporte no agrupado no se creó aparte: ID %d, %d reportes", second.ID, len(s.Reports))
+				}
+				return
+			}
+
+			if second.ID != first.ID || len(s.Reports) != 1 {
+				t.Errorf("el reporte agrupado no quedó en el incidente: ID %d, %d reportes", second.ID, len(s.Reports))
+			}
+			if !reflect.DeepEqual(second.MergedIDs, []int{first.ID + 1}) {
+				t.Errorf("MergedIDs = %v, esperado [%d]", second.MergedIDs, first.ID+1)
+			}
+			if second.Upvotes != tt.wantUpvotes || second.Votes != tt.wantUpvotes {
+				t.Errorf("confirmaciones = %d (votos %d), esperadas %d", second.Upvotes, second.Votes, tt.wantUpvotes)
+			}
+		})
+	}
+}
+
+func TestMergeDetails(t *testing.T) {
+	s := NewMemoryStorage()
+	first, _, _ := s.CreateReport(&models.Report{
+		Type: "closure", Severity: 3, Lanes: []int{1, 2},
+		Lat: testLat, Lng: testLng, Description: "Carril cerrado", UserID: 1,
+	})
+	merged, _, _ := s.CreateReport(&models.Report{
+		Type: "closure", Subtype: "roadworks", Severity: 5, Direction: "N", Lanes: []int{2, 3},
+		Lat: testLat + 0.0004, Lng: testLng, Description: "Obras en la vía", UserID: 2,
+	})
+
+	if merged.ID != first.ID {
+		t.Fatalf("no se agrupó: ID %d, esperado %d", merged.ID, first.ID)
+	}
+	if merged.Subtype != "roadworks" || merged.Direction != "N" || merged.Severity != 5 {
+		t.Errorf("subtipo %q, sentido %q, gravedad %d; esperados roadworks, N y 5", merged.Subtype, merged.Direction, merged.Severity)
+	}
+	if !reflect.DeepEqual(merged.Lanes, []int{1, 2, 3}) {
+		t.Errorf("carriles = %v, esperado [1 2 3]", merged.Lanes)
+	}
+	if want := []string{"Carril cerrado", "Obras en la vía"}; merged.Description != "Carril cerrado" || !reflect.DeepEqual(merged.Descriptions, want) {
+		t.Errorf("descripción %q y descripciones %v, esperado %v", merged.Description, merged.Descriptions, want)
+	}
+	if want := testLat + 0.0002; merged.Lat < want-1e-9 || merged.Lat > want+1e-9 {
+		t.Errorf("latitud = %v, esperado el promedio %v", merged.Lat, want)
+	}
+
+	// El ID asignado al duplicado no se reutiliza
+	other, _, _ := s.CreateReport(&models.Report{Type: "police", Lat: testLat, Lng: testLng})
+	if other.ID != first.ID+2 {
+		t.Errorf("ID del siguiente reporte = %d, esperado %d", other.ID, first.ID+2)
+	}
+}
+
+func TestMergeDismissals(t *testing.T) {
+	tests := []struct {
+		name           string
+		reporter       int // autor del reporte repetido, tras dos descartes de los usuarios 2 y 3
+		wantDismissals int
+		wantUpvotes    int
+	}{
+		{"el autor repite su reporte", 1, 2, 1},
+		{"quien lo descartó lo confirma", 2, 0, 2},
+		{"un nuevo usuario lo confirma", 4, 0, 2},
+		{"un reporte anónimo lo confirma", 0, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStorage()
+			report, _, _ := s.CreateReport(&models.Report{Type: "hazard", Lat: testLat, Lng: testLng, UserID: 1})
+			for _, userID := range []int{2, 3} {
+				if _, _, err := s.VoteReport(report.ID, userID, VoteDown); err != nil {
+					t.Fatal(err)
+				}
+			}
+			confirmedAt := s.Reports[report.ID].ConfirmedAt
+
+			merged, _, err := s.CreateReport(&models.Report{Type: "hazard", Lat: testLat, Lng: testLng, UserID: tt.reporter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merged.Dismissals != tt.wantDismissals || merged.Upvotes != tt.wantUpvotes {
+				t.Errorf("descartes seguidos %d y confirmaciones %d, esperados %d y %d",
+					merged.Dismissals, merged.Upvotes, tt.wantDismissals, tt.wantUpvotes)
+			}
+			if refreshed := !merged.ConfirmedAt.Equal(confirmedAt); refreshed != (tt.wantDismissals == 0) {
+				t.Errorf("confirmación renovada = %v", refreshed)
+			}
+		})
+	}
+}
+
+func TestVoteReport(t *testing.T) {
+	type vote struct {
+		userID, vote int
+		wantErr      error
+		wantRetired  bool
+	}
+	tests := []struct {
+		name  string
+		votes []vote
+		// contadores finales del reporte si sigue activo
+		wantUpvotes, wantDownvotes, wantDismissals int
+	}{
+		{
+			name:        "confirmación",
+			votes:       []vote{{userID: 2, vote: VoteUp}},
+			wantUpvotes: 2,
+		},
+		{
+			name:  "tres descartes lo retiran",
+			votes: []vote{{userID: 2, vote: VoteDown}, {userID: 3, vote: VoteDown}, {userID: 4, vote: VoteDown, wantRetired: true}},
+		},
+		{
+			name: "una confirmación reinicia los descartes",
+			votes: []vote{
+				{userID: 2, vote: VoteDown}, {userID: 3, vote: VoteDown},
+				{userID: 4, vote: VoteUp},
+				{userID: 5, vote: VoteDown}, {userID: 6, vote: VoteDown},
+			},
+			wantUpvotes: 2, wantDownvotes: 4, wantDismissals: 2,
+		},
+		{
+			name:        "voto repetido",
+			votes:       []vote{{userID: 2, vote: VoteUp}, {userID: 2, vote: VoteUp, wantErr: ErrAlreadyVoted}},
+			wantUpvotes: 2,
+		},
+		{
+			name:        "el autor ya confirmó su reporte",
+			votes:       []vote{{userID: 1, vote: VoteUp, wantErr: ErrAlreadyVoted}},
+			wantUpvotes: 1,
+		},
+		{
+			name:          "cambio de voto",
+			votes:         []vote{{userID: 2, vote: VoteUp}, {userID: 2, vote: VoteDown}},
+			wantUpvotes:   1,
+			wantDownvotes: 1, wantDismissals: 1,
+		},
+		{
+			name: "votar un reporte retirado",
+			votes: []vote{
+				{userID: 2, vote: VoteDown}, {userID: 3, vote: VoteDown}, {userID: 4, vote: VoteDown, wantRetired: true},
+				{userID: 5, vote: VoteUp, wantErr: ErrReportNotFound},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStorage()
+			report, _, err := s.CreateReport(&models.Report{Type: "hazard", Lat: testLat, Lng: testLng, UserID: 1})
+			if err != nil {
+				t.Fatalf("CreateReport: %v", err)
+			}
+
+			retired := false
+			for i, v := range tt.votes {
+				_, gotRetired, err := s.VoteReport(report.ID, v.userID, v.vote)
+				if !errors.Is(err, v.wantErr) {
+					t.Fatalf("voto %d retornó %v, esperado %v", i, err, v.wantErr)
+				}
+				if gotRetired != v.wantRetired {
+					t.Fatalf("voto %d: retirado = %v, esperado %v", i, gotRetired, v.wantRetired)
+				}
+				retired = retired || gotRetired
+			}
+
+			current, ok := s.Reports[report.ID]
+			if retired {
+				if ok || s.Voters[report.ID] != nil || len(s.ReportsWithin(testLat, testLng, 1)) != 0 {
+					t.Errorf("el reporte retirado sigue almacenado")
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("el reporte no sigue activo")
+			}
+			if current.Upvotes != tt.wantUpvotes || current.Downvotes != tt.wantDownvotes || current.Dismissals != tt.wantDismissals {
+				t.Errorf("confirmaciones %d, descartes %d, seguidos %d; esperados %d, %d y %d",
+					current.Upvotes, current.Downvotes, current.Dismissals, tt.wantUpvotes, tt.wantDownvotes, tt.wantDismissals)
+			}
+			if current.Votes != current.Upvotes-current.Downvotes {
+				t.Errorf("votos = %d, esperado %d", current.Votes, current.Upvotes-current.Downvotes)
+			}
+		})
+	}
+
+	s := NewMemoryStorage()
+	if _, _, err := s.VoteReport(42, 1, VoteUp); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("votar un reporte inexistente retornó %v, esperado %v", err, ErrReportNotFound)
+	}
+}
@@ -3,18 +3,30 @@ package services
 import (
 	"fmt"
 	"gowaze/models"
+	"gowaze/routing"
+	"log"
 	"time"
 )
 
+const (
+	// simulatedFreeFlow velocidad de flujo libre en km/h de las zonas simuladas
+	simulatedFreeFlow = 55.0
+	// simulatedClass clase de vía de las zonas simuladas
+	simulatedClass routing.RoadClass = "primary"
+)
+
 // TrafficService simula datos de tráfico en tiempo real
 type TrafficService struct {
-	storage *Storage
+	storage Storage
+	history *routing.SpeedHistory
 }
 
-// NewTrafficService crea una nueva instancia del servicio de tráfico
-func NewTrafficService(storage *Storage) *TrafficService {
+// NewTrafficService crea una nueva instancia del servicio de tráfico que sigue los
+// perfiles por hora de history
+func NewTrafficService(storage Storage, history *routing.SpeedHistory) *TrafficService {
 	return &TrafficService{
 		storage: storage,
+		history: history,
 	}
 }
 
@@ -32,14 +44,14 @@ func (ts *TrafficService) Start() {
 func (ts *TrafficService) simulateTrafficData() {
 	// Zonas de San Pedro Sula para simular tráfico
 	locations := []models.Location{
-		{14.0818, -87.2068}, // Centro - Plaza Central
-		{14.0900, -87.2100}, // Zona Norte - Bulevar
-		{14.0700, -87.2000}, // Zona Sur
-		{14.0800, -87.1900}, // Zona Este
-		{14.0750, -87.2200}, // Zona Oeste
-		{14.0950, -87.2150}, // Universidad UNAH
-		{14.0650, -87.2050}, // Hospital San Felipe
-		{14.0850, -87.1950}, // Mall Multiplaza
+		{Lat: 14.0818, Lng: -87.2068}, // Centro - Plaza Central
+		{Lat: 14.0900, Lng: -87.2100}, // Zona Norte - Bulevar
+		{Lat: 14.0700, Lng: -87.2000}, // Zona Sur
+		{Lat: 14.0800, Lng: -87.1900}, // Zona Este
+		{Lat: 14.0750, Lng: -87.2200}, // Zona Oeste
+		{Lat: 14.0950, Lng: -87.2150}, // Universidad UNAH
+		{Lat: 14.0650, Lng: -87.2050}, // Hospital San Felipe
+		{Lat: 14.0850, Lng: -87.1950}, // Mall Multiplaza
 	}
 
 	for i, loc := range locations {
@@ -57,24 +69,17 @@ func (ts *TrafficService) simulateTrafficData() {
 			Timestamp:  time.Now(),
 		}
 
-		ts.storage.UpdateTrafficData(key, trafficData)
+		if err := ts.storage.UpdateTrafficData(key, trafficData); err != nil {
+			log.Printf("Error guardando datos de tráfico %s: %v", key, err)
+		}
 	}
 }
 
 // calculateSpeed calcula la velocidad basada en diferentes factores
 func (ts *TrafficService) calculateSpeed(locationIndex int, loc models.Location) float64 {
-	hour := time.Now().Hour()
-	baseSpeed := 45.0
-
-	// Horas pico: reducir velocidad
-	if (hour >= 7 && hour <= 9) || (hour >= 17 && hour <= 19) {
-		baseSpeed = 25.0
-	}
-
-	// Velocidad nocturna más alta
-	if hour >= 22 || hour <= 5 {
-		baseSpeed = 55.0
-	}
+	// Perfil de la hora aprendido de los viajes o, sin suficientes, el típico: horas
+	// pico más lentas, noche más rápida
+	baseSpeed := simulatedFreeFlow * ts.history.Factor(simulatedClass, time.Now())
 
 	// Variabilidad por ubicación
 	locationVariation := float64((locationIndex*7 + int(time.Now().Unix())) % 20 - 10)
@@ -5,24 +5,43 @@ import (
 	"gowaze/models"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/websocket"
 )
 
+// wsClient conexión WebSocket con su estado por cliente
+type wsClient struct {
+	conn    *websocket.Conn
+	userID  int                // usuario de la sesión con que se conectó, 0 si no tiene
+	session *NavigationSession // viaje activo, nil si no está navegando
+	mu      sync.Mutex         // serializa las escrituras sobre la conexión
+}
+
+// write envía un mensaje JSON al cliente
+func (c *wsClient) write(msg interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
 // WebSocketService maneja las conexiones WebSocket
 type WebSocketService struct {
-	storage   *Storage
-	clients   map[*websocket.Conn]bool
-	broadcast chan []byte
-	upgrader  websocket.Upgrader
+	storage    Storage
+	navigation *NavigationService
+	clients    map[*websocket.Conn]*wsClient
+	broadcast  chan []byte
+	upgrader   websocket.Upgrader
+	mu         sync.RWMutex
 }
 
 // NewWebSocketService crea una nueva instancia del servicio WebSocket
-func NewWebSocketService(storage *Storage) *WebSocketService {
+func NewWebSocketService(storage Storage, navigation *NavigationService) *WebSocketService {
 	return &WebSocketService{
-		storage:   storage,
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan []byte),
+		storage:    storage,
+		navigation: navigation,
+		clients:    make(map[*websocket.Conn]*wsClient),
+		broadcast:  make(chan []byte),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // En producción, implementar verificación de origen
@@ -35,34 +54,67 @@ func NewWebSocketService(storage *Storage) *WebSocketService {
 func (ws *WebSocketService) HandleBroadcast() {
 	for {
 		msg := <-ws.broadcast
-		for client := range ws.clients {
-			err := client.WriteMessage(websocket.TextMessage, msg)
+		for _, client := range ws.snapshot() {
+			client.mu.Lock()
+			err := client.conn.WriteMessage(websocket.TextMessage, msg)
+			client.mu.Unlock()
 			if err != nil {
 				log.Printf("Error enviando mensaje WebSocket: %v", err)
-				client.Close()
-				delete(ws.clients, client)
+				ws.RemoveClient(client.conn)
 			}
 		}
 	}
 }
 
-// AddClient agrega un nuevo cliente WebSocket
-func (ws *WebSocketService) AddClient(conn *websocket.Conn) {
-	ws.clients[conn] = true
+// snapshot copia la lista de clientes para recorrerla sin mantener el lock
+func (ws *WebSocketService) snapshot() []*wsClient {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	clients := make([]*wsClient, 0, len(ws.clients))
+	for _, client := range ws.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// client retorna el estado de una conexión registrada
+func (ws *WebSocketService) client(conn *websocket.Conn) *wsClient {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.clients[conn]
+}
+
+// AddClient agrega un nuevo cliente WebSocket del usuario indicado, 0 si no tiene sesión
+func (ws *WebSocketService) AddClient(conn *websocket.Conn, userID int) {
+	ws.mu.Lock()
+	ws.clients[conn] = &wsClient{conn: conn, userID: userID}
+	total := len(ws.clients)
+	ws.mu.Unlock()
 
 	// Enviar estadísticas iniciales
 	ws.SendStatsToClient(conn)
 
-	log.Printf("🔌 Nuevo cliente WebSocket conectado. Total: %d", len(ws.clients))
+	log.Printf("🔌 Nuevo cliente WebSocket conectado. Total: %d", total)
 }
 
-// RemoveClient remueve un cliente WebSocket
+// RemoveClient remueve un cliente WebSocket y guarda su viaje activo, si lo hay
 func (ws *WebSocketService) RemoveClient(conn *websocket.Conn) {
-	if _, ok := ws.clients[conn]; ok {
+	var session *NavigationSession
+	ws.mu.Lock()
+	if client, ok := ws.clients[conn]; ok {
 		delete(ws.clients, conn)
 		conn.Close()
+		// La sesión se toma con el lock: StartNavigation y StopNavigation la modifican
+		session = client.session
+		client.session = nil
 		log.Printf("🔌 Cliente WebSocket desconectado. Total: %d", len(ws.clients))
 	}
+	ws.mu.Unlock()
+
+	if session != nil {
+		ws.navigation.Finish(session)
+	}
 }
 
 // BroadcastStats envía estadísticas a todos los clientes
@@ -105,10 +157,70 @@ func (ws *WebSocketService) BroadcastNewReport(report *models.Report) {
 
 	// También enviar estadísticas actualizadas
 	ws.BroadcastStats()
+
+	// Recalcular los viajes activos que pasan por el reporte
+	go ws.rerouteForReport(report)
+}
+
+// PublishVote difunde el resultado de un voto: el reporte actualizado o, si fue
+// retirado, su eliminación para que los clientes lo quiten del mapa
+func (ws *WebSocketService) PublishVote(report *models.Report, retired bool) {
+	msg := models.WebSocketMessage{
+		Type:   "report_updated",
+		Report: report,
+	}
+	if retired {
+		msg.Type = "report_removed"
+		msg.Reports = ws.storage.GetRecentReports()
+		log.Printf("🗑️  Reporte %d (%s) retirado por votos negativos", report.ID, report.Type)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error serializando reporte: %v", err)
+		return
+	}
+
+	ws.broadcast <- data
+	if retired {
+		ws.BroadcastStats()
+	}
+}
+
+// VoteReport procesa el voto del usuario de un cliente sobre un reporte y difunde el
+// resultado. Los errores, incluido votar sin sesión, se notifican solo a ese cliente
+func (ws *WebSocketService) VoteReport(conn *websocket.Conn, reportID, vote int) {
+	client := ws.client(conn)
+	if client == nil {
+		return
+	}
+	if client.userID == 0 {
+		ws.sendReportError(client, ErrNoSession)
+		return
+	}
+
+	report, retired, err := ws.storage.VoteReport(reportID, client.userID, vote)
+	if err != nil {
+		ws.sendReportError(client, err)
+		return
+	}
+	ws.PublishVote(report, retired)
+}
+
+// sendReportError notifica a un cliente que su acción sobre un reporte falló
+func (ws *WebSocketService) sendReportError(client *wsClient, err error) {
+	if err := client.write(models.WebSocketMessage{Type: "report_error", Error: err.Error()}); err != nil {
+		log.Printf("Error enviando error de voto: %v", err)
+	}
 }
 
 // SendStatsToClient envía estadísticas a un cliente específico
 func (ws *WebSocketService) SendStatsToClient(conn *websocket.Conn) {
+	client := ws.client(conn)
+	if client == nil {
+		return
+	}
+
 	usersOnline, totalReports, trafficPoints := ws.storage.GetStats()
 	reports := ws.storage.GetRecentReports()
 
@@ -120,11 +232,150 @@ func (ws *WebSocketService) SendStatsToClient(conn *websocket.Conn) {
 		Reports:       reports,
 	}
 
-	if err := conn.WriteJSON(msg); err != nil {
+	if err := client.write(msg); err != nil {
 		log.Printf("Error enviando estadísticas iniciales: %v", err)
 	}
 }
 
+// StartNavigation inicia un viaje para el cliente y le envía la ruta inicial
+func (ws *WebSocketService) StartNavigation(conn *websocket.Conn, req RouteRequest) {
+	client := ws.client(conn)
+	if client == nil {
+		return
+	}
+
+	session, update, err := ws.navigation.Start(req, client.userID)
+	if err != nil {
+		ws.sendNavigationError(client, err)
+		return
+	}
+
+	ws.mu.Lock()
+	_, connected := ws.clients[conn]
+	previous := client.session
+	if connected {
+		client.session = session
+	}
+	ws.mu.Unlock()
+
+	if previous != nil {
+		ws.navigation.Finish(previous)
+	}
+	if !connected {
+		// Se desconectó mientras se calculaba la ruta: RemoveClient ya no verá esta sesión
+		ws.navigation.Finish(session)
+		return
+	}
+	log.Printf("🧭 Navegación %s iniciada: %.2f km", session.ID, update.Remaining)
+	ws.sendNavigation(client, "nav_started", update)
+}
+
+// UpdateNavigation procesa una nueva posición del cliente durante un viaje
+func (ws *WebSocketService) UpdateNavigation(conn *websocket.Conn, position models.Location) {
+	client := ws.client(conn)
+	session := ws.session(client)
+	if session == nil {
+		ws.sendNavigationError(client, ErrNoNavigation)
+		return
+	}
+
+	update, err := ws.navigation.UpdatePosition(session, position)
+	if err != nil {
+		log.Printf("Error recalculando ruta de %s: %v", session.ID, err)
+	}
+	if update.Status == NavigationArrived {
+		update.TripID = ws.navigation.Finish(session).ID
+	}
+	ws.sendNavigation(client, navigationMessageType(update), update)
+}
+
+// StopNavigation termina el viaje activo del cliente
+func (ws *WebSocketService) StopNavigation(conn *websocket.Conn) {
+	client := ws.client(conn)
+	if client == nil {
+		return
+	}
+
+	ws.mu.Lock()
+	session := client.session
+	client.session = nil
+	ws.mu.Unlock()
+
+	if session != nil {
+		trip := ws.navigation.Finish(session)
+		log.Printf("🧭 Navegación %s finalizada: viaje %d", session.ID, trip.ID)
+		ws.sendNavigation(client, "nav_stopped", &models.NavigationUpdate{SessionID: session.ID, TripID: trip.ID})
+	}
+}
+
+// rerouteForReport recalcula los viajes activos afectados por un nuevo reporte
+func (ws *WebSocketService) rerouteForReport(report *models.Report) {
+	for _, client := range ws.snapshot() {
+		session := ws.session(client)
+		if session == nil {
+			continue
+		}
+
+		update, err := ws.navigation.ReportAdded(session, report)
+		if err != nil {
+			log.Printf("Error recalculando ruta de %s: %v", session.ID, err)
+			continue
+		}
+		if update != nil {
+			ws.sendNavigation(client, navigationMessageType(update), update)
+		}
+	}
+}
+
+// session retorna el viaje activo de un cliente
+func (ws *WebSocketService) session(client *wsClient) *NavigationSession {
+	if client == nil {
+		return nil
+	}
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return client.session
+}
+
+// sendNavigation envía un estado de navegación al cliente
+func (ws *WebSocketService) sendNavigation(client *wsClient, msgType string, update *models.NavigationUpdate) {
+	if client == nil {
+		return
+	}
+	msg := models.WebSocketMessage{
+		Type:       msgType,
+		Navigation: update,
+	}
+	if err := client.write(msg); err != nil {
+		log.Printf("Error enviando navegación: %v", err)
+	}
+}
+
+// sendNavigationError notifica al cliente un error de navegación
+func (ws *WebSocketService) sendNavigationError(client *wsClient, err error) {
+	if client == nil {
+		return
+	}
+	msg := models.WebSocketMessage{
+		Type:  "nav_error",
+		Error: err.Error(),
+	}
+	if err := client.write(msg); err != nil {
+		log.Printf("Error enviando navegación: %v", err)
+	}
+}
+
+// navigationMessageType tipo de mensaje WebSocket según el estado de la sesión
+func navigationMessageType(update *models.NavigationUpdate) string {
+	switch update.Status {
+	case NavigationRerouted:
+		return "nav_rerouted"
+	case NavigationArrived:
+		return "nav_arrived"
+	}
+	return "nav_update"
+}
+
 // GetUpgrader retorna el upgrader de WebSocket
 func (ws *WebSocketService) GetUpgrader() *websocket.Upgrader {
 	return &ws.upgrader
@@ -132,5 +383,7 @@ func (ws *WebSocketService) GetUpgrader() *websocket.Upgrader {
 
 // GetClientCount retorna el número de clientes conectados
 func (ws *WebSocketService) GetClientCount() int {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
 	return len(ws.clients)
 }
@@ -7,6 +7,7 @@ let routeControl;
 let ws;
 let reconnectInterval;
 let routeWaypoints = [];
+let reportTypes = {};
 
 // Inicialización cuando carga el DOM
 document.addEventListener('DOMContentLoaded', function() {
@@ -17,6 +18,7 @@ document.addEventListener('DOMContentLoaded', function() {
 function initializeApp() {
     console.log('🚀 Inicializando GoWaze...');
     initMap();
+    loadReportTypes();
     connectWebSocket();
     setupEventListeners();
     console.log('✅ GoWaze inicializado correctamente');
@@ -43,6 +45,11 @@ function initMap() {
 
 // Configurar eventos del mapa
 function setupMapEvents() {
+    // Recargar la lista de reportes con el área visible
+    map.on('moveend', function() {
+        htmx.trigger('#reports-container', 'refresh');
+    });
+
     // Click derecho para reportar
     map.on('contextmenu', function(e) {
         const lat = e.latlng.lat.toFixed(6);
@@ -307,6 +314,29 @@ function clearRoute() {
     console.log('✅ Ruta limpiada');
 }
 
+// Cargar el registro de tipos y subtipos de reporte
+function loadReportTypes() {
+    fetch('/api/v1/report-types')
+        .then(response => response.json())
+        .then(types => {
+            types.forEach(type => { reportTypes[type.name] = type; });
+        })
+        .catch(error => console.error('❌ Error cargando tipos de reporte:', error));
+}
+
+// Icono y nombre de un reporte según el registro de tipos
+function reportTypeInfo(report) {
+    const type = reportTypes[report.type];
+    if (!type) {
+        return { icon: '📍', label: report.type.toUpperCase() };
+    }
+    const subtype = (type.subtypes || []).find(s => s.name === report.subtype);
+    return {
+        icon: (subtype && subtype.icon) || type.icon,
+        label: subtype ? `${type.label} - ${subtype.label}` : type.label
+    };
+}
+
 // Actualizar marcadores de reportes
 function updateReportMarkers(reports) {
     console.log(`📍 Actualizando ${reports.length} marcadores de reportes`);
@@ -316,16 +346,11 @@ function updateReportMarkers(reports) {
     reportMarkers = [];
 
     reports.forEach(report => {
-        const icons = {
-            accident: '🚗',
-            police: '👮',
-            traffic: '🚦',
-            hazard: '⚠️'
-        };
+        const info = reportTypeInfo(report);
 
         const marker = L.marker([report.lat, report.lng], {
             icon: L.divIcon({
-                html: `<div class="custom-marker marker-${report.type}">${icons[report.type]}</div>`,
+                html: `<div class="custom-marker marker-${report.type}">${info.icon}</div>`,
                 iconSize: [20, 20],
                 iconAnchor: [10, 10]
             })
@@ -333,9 +358,11 @@ function updateReportMarkers(reports) {
 
         marker.bindPopup(`
             <div style="min-width: 200px;">
-                <strong>${icons[report.type]} ${report.type.toUpperCase()}</strong><br>
+                <strong>${info.icon} ${info.label}</strong><br>
                 <p style="margin: 10px 0;">${report.description}</p>
+                ${(report.photos || []).map(photo => `<a href="${photo.url}" target="_blank"><img src="${photo.thumbnail_url}" alt="Foto del reporte" style="height: 60px; margin: 0 4px 8px 0; border-radius: 4px;"></a>`).join('')}
                 <small style="color: #666;">
+                    🔥 Gravedad ${report.severity}/5${report.direction ? ` | Sentido ${report.direction}` : ''}${report.lanes ? ` | Carriles ${report.lanes.join(', ')}` : ''}<br>
                     📅 ${new Date(report.created_at).toLocaleString()}<br>
                     👍 ${report.votes} votos
                 </small>
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"gowaze/models"
+	"io"
+	"strings"
+	"time"
+)
+
+// gpx documento GPX 1.1
+type gpx struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Version   string     `xml:"version,attr"`
+	Creator   string     `xml:"creator,attr"`
+	Namespace string     `xml:"xmlns,attr"`
+	Metadata  gpxMeta    `xml:"metadata"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Routes    []gpxRoute `xml:"rte"`
+	Tracks    []gpxTrack `xml:"trk"`
+}
+
+type gpxMeta struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+	Name string  `xml:"name,omitempty"`
+	Desc string  `xml:"desc,omitempty"`
+	Type string  `xml:"type,omitempty"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Type     string       `xml:"type,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+// WriteRouteGPX exporta una ruta calculada a GPX 1.1: las paradas como waypoints,
+// las instrucciones como puntos de ruta (rte) y la geometría completa como track
+func WriteRouteGPX(w io.Writer, route *models.Route, name string) error {
+	doc := newGPX(name, fmt.Sprintf("%.2f km, %d min", route.Distance, route.Duration))
+
+	doc.Waypoints = append(doc.Waypoints, gpxPoint{Lat: route.From.Lat, Lon: route.From.Lng, Name: "Origen"})
+	for i, wp := range route.Waypoints {
+		doc.Waypoints = append(doc.Waypoints, gpxPoint{Lat: wp.Lat, Lon: wp.Lng, Name: fmt.Sprintf("Parada %d", i+1)})
+	}
+	doc.Waypoints = append(doc.Waypoints, gpxPoint{Lat: route.To.Lat, Lon: route.To.Lng, Name: "Destino"})
+
+	if len(route.Steps) > 0 {
+		rte := gpxRoute{Name: name}
+		for _, step := range route.Steps {
+			rte.Points = append(rte.Points, gpxPoint{
+				Lat:  step.Location.Lat,
+				Lon:  step.Location.Lng,
+				Name: step.Street,
+				Desc: step.Instruction,
+				Type: step.Maneuver,
+			})
+		}
+		doc.Routes = append(doc.Routes, rte)
+	}
+
+	segment := gpxSegment{}
+	for _, p := range route.Points {
+		segment.Points = append(segment.Points, gpxPoint{Lat: p.Lat, Lon: p.Lng})
+	}
+	doc.Tracks = append(doc.Tracks, gpxTrack{Name: name, Type: route.Profile, Segments: []gpxSegment{segment}})
+
+	return writeXML(w, doc)
+}
+
+// WriteTripGPX exporta un viaje registrado a GPX 1.1 como track con marcas de tiempo
+func WriteTripGPX(w io.Writer, trip *models.Trip, name string) error {
+	doc := newGPX(name, fmt.Sprintf("%.2f km", trip.Distance))
+
+	segment := gpxSegment{}
+	for _, p := range trip.Points {
+		segment.Points = append(segment.Points, gpxPoint{Lat: p.Lat, Lon: p.Lng, Time: gpxTime(p.Timestamp)})
+	}
+	doc.Tracks = append(doc.Tracks, gpxTrack{Name: name, Type: trip.Profile, Segments: []gpxSegment{segment}})
+
+	return writeXML(w, doc)
+}
+
+func newGPX(name, desc string) *gpx {
+	return &gpx{
+		Version:   "1.1",
+		Creator:   "GoWaze",
+		Namespace: "http://www.topografix.com/GPX/1/1",
+		Metadata:  gpxMeta{Name: name, Desc: desc, Time: gpxTime(time.Now())},
+	}
+}
+
+// gpxTime formatea un instante en UTC como exige GPX
+func gpxTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// kml documento KML 2.2
+type kml struct {
+	XMLName   xml.Name    `xml:"kml"`
+	Namespace string      `xml:"xmlns,attr"`
+	Document  kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name        string         `xml:"name"`
+	Description string         `xml:"description,omitempty"`
+	Placemarks  []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string         `xml:"name"`
+	Description string         `xml:"description,omitempty"`
+	TimeSpan    *kmlTimeSpan   `xml:"TimeSpan,omitempty"`
+	Point       *kmlGeometry   `xml:"Point,omitempty"`
+	LineString  *kmlLineString `xml:"LineString,omitempty"`
+}
+
+type kmlTimeSpan struct {
+	Begin string `xml:"begin"`
+	End   string `xml:"end"`
+}
+
+type kmlGeometry struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteRouteKML exporta una ruta calculada a KML 2.2 con la línea de la ruta, las
+// paradas y una marca por instrucción
+func WriteRouteKML(w io.Writer, route *models.Route, name string) error {
+	doc := newKML(name, fmt.Sprintf("%.2f km, %d min", route.Distance, route.Duration))
+	doc.Document.Placemarks = append(doc.Document.Placemarks,
+		kmlPlacemark{Name: name, LineString: &kmlLineString{Tessellate: 1, Coordinates: kmlCoordinates(route.Points)}},
+		kmlPoint("Origen", "", route.From),
+	)
+	for i, wp := range route.Waypoints {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPoint(fmt.Sprintf("Parada %d", i+1), "", wp))
+	}
+	doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPoint("Destino", "", route.To))
+
+	for i, step := range route.Steps {
+		doc.Document.Placemarks = append(doc.Document.Placemarks,
+			kmlPoint(fmt.Sprintf("%d. %s", i+1, step.Instruction), FormatDistance(step.Distance), step.Location))
+	}
+
+	return writeXML(w, doc)
+}
+
+// WriteTripKML exporta un viaje registrado a KML 2.2 con su recorrido y duración
+func WriteTripKML(w io.Writer, trip *models.Trip, name string) error {
+	doc := newKML(name, fmt.Sprintf("%.2f km", trip.Distance))
+
+	points := make([]models.Location, len(trip.Points))
+	for i, p := range trip.Points {
+		points[i] = models.Location{Lat: p.Lat, Lng: p.Lng}
+	}
+	doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+		Name:       name,
+		TimeSpan:   &kmlTimeSpan{Begin: gpxTime(trip.StartedAt), End: gpxTime(trip.EndedAt)},
+		LineString: &kmlLineString{Tessellate: 1, Coordinates: kmlCoordinates(points)},
+	})
+
+	return writeXML(w, doc)
+}
+
+func newKML(name, desc string) *kml {
+	return &kml{
+		Namespace: "http://www.opengis.net/kml/2.2",
+		Document:  kmlDocument{Name: name, Description: desc},
+	}
+}
+
+func kmlPoint(name, desc string, p models.Location) kmlPlacemark {
+	return kmlPlacemark{Name: name, Description: desc, Point: &kmlGeometry{Coordinates: kmlCoordinates([]models.Location{p})}}
+}
+
+// kmlCoordinates formatea puntos como "lng,lat,0" separados por espacios
+func kmlCoordinates(points []models.Location) string {
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = fmt.Sprintf("%.6f,%.6f,0", p.Lng, p.Lat)
+	}
+	return strings.Join(coords, " ")
+}
+
+// writeXML escribe un documento XML con encabezado e indentación
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
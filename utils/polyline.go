@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"fmt"
+	"gowaze/models"
+	"math"
+	"strings"
+)
+
+// Precisiones del formato de polilínea codificada de Google
+const (
+	PolylinePrecision  = 5 // 1e-5 grados, usada por Google Maps y Leaflet
+	PolylinePrecision6 = 6 // 1e-6 grados, usada por OSRM y Valhalla
+)
+
+// EncodePolyline codifica una secuencia de puntos con el algoritmo de polilíneas de Google
+func EncodePolyline(points []models.Location, precision int) string {
+	factor := math.Pow(10, float64(precision))
+	var b strings.Builder
+	prevLat, prevLng := int64(0), int64(0)
+
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * factor))
+		lng := int64(math.Round(p.Lng * factor))
+		encodeValue(&b, lat-prevLat)
+		encodeValue(&b, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+
+	return b.String()
+}
+
+// encodeValue agrega un valor con signo codificado en bloques de 5 bits
+func encodeValue(b *strings.Builder, value int64) {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+	for v >= 0x20 {
+		b.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	b.WriteByte(byte(v + 63))
+}
+
+// DecodePolyline decodifica una polilínea de Google con la precisión indicada
+func DecodePolyline(encoded string, precision int) ([]models.Location, error) {
+	factor := math.Pow(10, float64(precision))
+	var points []models.Location
+	lat, lng := int64(0), int64(0)
+
+	for i := 0; i < len(encoded); {
+		dLat, next, err := decodeValue(encoded, i)
+		if err != nil {
+			return nil, err
+		}
+		dLng, next, err := decodeValue(encoded, next)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		lat += dLat
+		lng += dLng
+		points = append(points, models.Location{Lat: float64(lat) / factor, Lng: float64(lng) / factor})
+	}
+
+	return points, nil
+}
+
+// decodeValue lee un valor con signo a partir de la posición i y retorna la siguiente posición
+func decodeValue(encoded string, i int) (int64, int, error) {
+	var result int64
+	shift := uint(0)
+	for {
+		if i >= len(encoded) {
+			return 0, i, fmt.Errorf("polilínea truncada")
+		}
+		c := int64(encoded[i]) - 63
+		i++
+		if c < 0 || c > 0x3f || shift > 60 {
+			return 0, i, fmt.Errorf("polilínea inválida en la posición %d", i-1)
+		}
+		result |= (c & 0x1f) << shift
+		shift += 5
+		if c < 0x20 {
+			break
+		}
+	}
+
+	if result&1 != 0 {
+		return ^(result >> 1), i, nil
+	}
+	return result >> 1, i, nil
+}
+
+// LineString convierte una secuencia de puntos en una geometría GeoJSON LineString
+func LineString(points []models.Location) models.Geometry {
+	coordinates := make([][]float64, len(points))
+	for i, p := range points {
+		coordinates[i] = []float64{p.Lng, p.Lat}
+	}
+	return models.Geometry{Type: "LineString", Coordinates: coordinates}
+}
+
+// Polygon convierte un anillo de puntos en una geometría GeoJSON Polygon, cerrándolo si hace falta
+func Polygon(ring []models.Location) models.Geometry {
+	coordinates := make([][]float64, 0, len(ring)+1)
+	for _, p := range ring {
+		coordinates = append(coordinates, []float64{p.Lng, p.Lat})
+	}
+	if n := len(ring); n > 0 && ring[0] != ring[n-1] {
+		coordinates = append(coordinates, coordinates[0])
+	}
+	return models.Geometry{Type: "Polygon", Coordinates: [][][]float64{coordinates}}
+}
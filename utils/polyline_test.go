@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"gowaze/models"
+)
+
+func TestPolylineRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    []models.Location
+		precision int
+		encoded   string // vacío si no se compara con una codificación conocida
+	}{
+		{
+			name:      "ejemplo de Google",
+			points:    []models.Location{{Lat: 38.5, Lng: -120.2}, {Lat: 40.7, Lng: -120.95}, {Lat: 43.252, Lng: -126.453}},
+			precision: PolylinePrecision,
+			encoded:   "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			name:      "sin puntos",
+			precision: PolylinePrecision,
+			encoded:   "",
+		},
+		{
+			name:      "precisión 6",
+			points:    []models.Location{{Lat: 14.081812, Lng: -87.206843}, {Lat: 14.090001, Lng: -87.21}, {Lat: 14.075, Lng: -87.220009}},
+			precision: PolylinePrecision6,
+		},
+		{
+			name:      "puntos repetidos y signos opuestos",
+			points:    []models.Location{{Lat: -33.4489, Lng: 70.6693}, {Lat: -33.4489, Lng: 70.6693}, {Lat: 0, Lng: 0}, {Lat: 89.99999, Lng: -179.99999}},
+			precision: PolylinePrecision,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := EncodePolyline(tt.points, tt.precision)
+			if tt.encoded != "" && encoded != tt.encoded {
+				t.Fatalf("EncodePolyline = %q, esperado %q", encoded, tt.encoded)
+			}
+
+			decoded, err := DecodePolyline(encoded, tt.precision)
+			if err != nil {
+				t.Fatalf("DecodePolyline: %v", err)
+			}
+			if len(decoded) != len(tt.points) {
+				t.Fatalf("DecodePolyline retornó %d puntos, esperados %d", len(decoded), len(tt.points))
+			}
+			tolerance := math.Pow(10, -float64(tt.precision)) / 2
+			for i, p := range tt.points {
+				if math.Abs(decoded[i].Lat-p.Lat) > tolerance || math.Abs(decoded[i].Lng-p.Lng) > tolerance {
+					t.Errorf("punto %d = %v, esperado %v", i, decoded[i], p)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodePolylineInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"truncada", "_p~iF~ps|U_ulL"},
+		{"valor sin terminar", "_p~iF~"},
+		{"carácter fuera de rango", "_p~iF ps|U"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodePolyline(tt.encoded, PolylinePrecision); err == nil {
+				t.Errorf("DecodePolyline(%q) no retornó error", tt.encoded)
+			}
+		})
+	}
+}
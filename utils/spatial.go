@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// spatialCellSize tamaño de celda del índice de puntos en grados (~1.1 km)
+const spatialCellSize = 0.01
+
+// kmPerDegree distancia aproximada de un grado de latitud en km
+const kmPerDegree = 111.32
+
+// BBox rectángulo delimitado por latitudes y longitudes mínimas y máximas
+type BBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+// Contains indica si un punto está dentro del rectángulo
+func (b BBox) Contains(lat, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// SpatialHit punto encontrado por una consulta del índice
+type SpatialHit struct {
+	Key      string
+	Lat      float64
+	Lng      float64
+	Distance float64 // en km desde el centro de la consulta (0 en consultas por rectángulo)
+}
+
+type spatialCell struct {
+	x, y int
+}
+
+type spatialPoint struct {
+	lat, lng float64
+	cell     spatialCell
+}
+
+// PointIndex índice espacial de puntos identificados por clave, organizado en celdas
+// regulares. No es seguro para uso concurrente: quien lo mantiene debe sincronizarlo
+type PointIndex struct {
+	points map[string]spatialPoint
+	cells  map[spatialCell]map[string]struct{}
+}
+
+// NewPointIndex crea un índice vacío
+func NewPointIndex() *PointIndex {
+	return &PointIndex{
+		points: make(map[string]spatialPoint),
+		cells:  make(map[spatialCell]map[string]struct{}),
+	}
+}
+
+func spatialCoord(deg float64) int {
+	return int(math.Floor(deg / spatialCellSize))
+}
+
+// Len retorna la cantidad de puntos indexados
+func (idx *PointIndex) Len() int {
+	return len(idx.points)
+}
+
+// Insert agrega un punto o mueve uno existente con la misma clave
+func (idx *PointIndex) Insert(key string, lat, lng float64) {
+	idx.Remove(key)
+
+	cell := spatialCell{spatialCoord(lng), spatialCoord(lat)}
+	idx.points[key] = spatialPoint{lat: lat, lng: lng, cell: cell}
+	keys, ok := idx.cells[cell]
+	if !ok {
+		keys = make(map[string]struct{})
+		idx.cells[cell] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// Remove quita un punto del índice
+func (idx *PointIndex) Remove(key string) {
+	point, ok := idx.points[key]
+	if !ok {
+		return
+	}
+	delete(idx.points, key)
+	if keys := idx.cells[point.cell]; keys != nil {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(idx.cells, point.cell)
+		}
+	}
+}
+
+// InBox retorna los puntos dentro de un rectángulo
+func (idx *PointIndex) InBox(box BBox) []SpatialHit {
+	hits := make([]SpatialHit, 0)
+	minX, maxX := spatialCoord(box.MinLng), spatialCoord(box.MaxLng)
+	minY, maxY := spatialCoord(box.MinLat), spatialCoord(box.MaxLat)
+
+	// Con un rectángulo enorme conviene recorrer las celdas ocupadas
+	if (maxX-minX+1)*(maxY-minY+1) > len(idx.cells) {
+		for cell, keys := range idx.cells {
+			if cell.x >= minX && cell.x <= maxX && cell.y >= minY && cell.y <= maxY {
+				hits = idx.collectBox(hits, keys, box)
+			}
+		}
+		return hits
+	}
+
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			if keys, ok := idx.cells[spatialCell{x, y}]; ok {
+				hits = idx.collectBox(hits, keys, box)
+			}
+		}
+	}
+	return hits
+}
+
+func (idx *PointIndex) collectBox(hits []SpatialHit, keys map[string]struct{}, box BBox) []SpatialHit {
+	for key := range keys {
+		p := idx.points[key]
+		if box.Contains(p.lat, p.lng) {
+			hits = append(hits, SpatialHit{Key: key, Lat: p.lat, Lng: p.lng})
+		}
+	}
+	return hits
+}
+
+// Within retorna los puntos a menos de radius km de un centro, ordenados por distancia
+func (idx *PointIndex) Within(lat, lng, radius float64) []SpatialHit {
+	latSpan := radius / kmPerDegree
+	lngSpan := radius / (kmPerDegree * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	box := BBox{MinLat: lat - latSpan, MinLng: lng - lngSpan, MaxLat: lat + latSpan, MaxLng: lng + lngSpan}
+
+	hits := idx.InBox(box)
+	within := hits[:0]
+	for _, hit := range hits {
+		hit.Distance = HaversineDistance(lat, lng, hit.Lat, hit.Lng)
+		if hit.Distance <= radius {
+			within = append(within, hit)
+		}
+	}
+	sortHits(within)
+	return within
+}
+
+// Nearest retorna los k puntos más cercanos a un centro, ordenados por distancia.
+// Recorre anillos de celdas crecientes hasta que ningún anillo más lejano pueda
+// contener un punto más cercano que el k-ésimo encontrado
+func (idx *PointIndex) Nearest(lat, lng float64, k int) []SpatialHit {
+	if k <= 0 || len(idx.points) == 0 {
+		return []SpatialHit{}
+	}
+	if k > len(idx.points) {
+		k = len(idx.points)
+	}
+
+	// Anillo máximo necesario para cubrir todas las celdas ocupadas
+	cx, cy := spatialCoord(lng), spatialCoord(lat)
+	maxRing := 0
+	for cell := range idx.cells {
+		ring := maxInt(absInt(cell.x-cx), absInt(cell.y-cy))
+		if ring > maxRing {
+			maxRing = ring
+		}
+	}
+
+	// Distancia mínima en km que separa el centro de las celdas de un anillo
+	cellKm := spatialCellSize * kmPerDegree * math.Max(math.Cos(lat*math.Pi/180), 0.01)
+
+	hits := make([]SpatialHit, 0, k)
+	for ring := 0; ring <= maxRing; ring++ {
+		if len(hits) >= k && hits[k-1].Distance <= float64(ring-1)*cellKm {
+			break
+		}
+		for x := cx - ring; x <= cx+ring; x++ {
+			for y := cy - ring; y <= cy+ring; y++ {
+				if absInt(x-cx) != ring && absInt(y-cy) != ring {
+					continue // interior, ya recorrido
+				}
+				for key := range idx.cells[spatialCell{x, y}] {
+					p := idx.points[key]
+					hits = append(hits, SpatialHit{Key: key, Lat: p.lat, Lng: p.lng, Distance: HaversineDistance(lat, lng, p.lat, p.lng)})
+				}
+			}
+		}
+		sortHits(hits)
+	}
+
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+func sortHits(hits []SpatialHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Distance < hits[j].Distance
+	})
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
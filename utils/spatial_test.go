@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// testIndex índice con puntos sobre el ecuador separados por 0.005° (~0.56 km),
+// de modo que ocupan celdas distintas y sus distancias son fáciles de razonar
+func testIndex() *PointIndex {
+	idx := NewPointIndex()
+	points := map[string][2]float64{
+		"a": {0, 0},
+		"b": {0, 0.005},
+		"c": {0, 0.01},
+		"d": {0, 0.02},
+		"e": {0, -0.05},
+		"f": {0.5, 0.5},
+	}
+	for key, p := range points {
+		idx.Insert(key, p[0], p[1])
+	}
+	return idx
+}
+
+func hitKeys(hits []SpatialHit) []string {
+	keys := make([]string, len(hits))
+	for i, hit := range hits {
+		keys[i] = hit.Key
+	}
+	return keys
+}
+
+func TestPointIndexNearest(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		k        int
+		want     []string
+	}{
+		{"el más cercano", 0, 0.001, 1, []string{"a"}},
+		{"ordenados por distancia", 0, 0.009, 3, []string{"c", "b", "a"}},
+		{"anillos lejanos", 0, -0.04, 2, []string{"e", "a"}},
+		{"k mayor que el índice", 0.5, 0.5, 10, []string{"f", "d", "c", "b", "a", "e"}},
+		{"k cero", 0, 0, 0, []string{}},
+	}
+
+	idx := testIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := idx.Nearest(tt.lat, tt.lng, tt.k)
+			if got := hitKeys(hits); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Nearest(%v, %v, %d) = %v, esperado %v", tt.lat, tt.lng, tt.k, got, tt.want)
+			}
+			for i := 1; i < len(hits); i++ {
+				if hits[i].Distance < hits[i-1].Distance {
+					t.Errorf("distancias desordenadas: %v", hits)
+				}
+			}
+		})
+	}
+}
+
+func TestPointIndexWithin(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		radius   float64
+		want     []string
+	}{
+		{"solo el centro", 0, 0, 0.1, []string{"a"}},
+		{"radio de un km", 0, 0, 1, []string{"a", "b"}},
+		{"radio que cruza celdas", 0, 0.012, 1.2, []string{"c", "b", "d"}},
+		{"sin puntos", 1, 1, 5, []string{}},
+	}
+
+	idx := testIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := idx.Within(tt.lat, tt.lng, tt.radius)
+			if got := hitKeys(hits); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Within(%v, %v, %v) = %v, esperado %v", tt.lat, tt.lng, tt.radius, got, tt.want)
+			}
+			for _, hit := range hits {
+				if hit.Distance > tt.radius {
+					t.Errorf("%s a %.3f km, fuera del radio %.3f", hit.Key, hit.Distance, tt.radius)
+				}
+			}
+		})
+	}
+}
+
+func TestPointIndexUpdates(t *testing.T) {
+	idx := testIndex()
+
+	// Mover un punto lo saca de su celda anterior
+	idx.Insert("f", 0, 0.006)
+	if got := hitKeys(idx.Nearest(0, 0.006, 1)); !reflect.DeepEqual(got, []string{"f"}) {
+		t.Errorf("tras mover f, Nearest = %v", got)
+	}
+	if hits := idx.Within(0.5, 0.5, 1); len(hits) != 0 {
+		t.Errorf("f sigue en su ubicación anterior: %v", hitKeys(hits))
+	}
+
+	idx.Remove("a")
+	idx.Remove("inexistente")
+	if idx.Len() != 5 {
+		t.Errorf("Len = %d, esperado 5", idx.Len())
+	}
+	keys := hitKeys(idx.InBox(BBox{MinLat: -1, MinLng: -1, MaxLat: 1, MaxLng: 1}))
+	sort.Strings(keys)
+	if want := []string{"b", "c", "d", "e", "f"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("InBox = %v, esperado %v", keys, want)
+	}
+}